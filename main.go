@@ -2,14 +2,52 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	kache "github.com/falldio/Kache/pkg"
 	"github.com/falldio/Kache/pkg/config"
+	"github.com/falldio/Kache/pkg/logger"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-func main() {
+// db is the slow data source behind every group of the demo node
+var db = map[string]string{
+	"Tom":  "630",
+	"Jack": "589",
+	"Sam":  "567",
+}
+
+func loadConfig() {
+	pflag.StringP("port", "p", "5658", "kache Port")
+	pflag.BoolP("api", "a", true, "Start a api server?")
+	pflag.String("api_port", "9999", "Port of the api server")
+	pflag.Bool("metrics", false, "Serve Prometheus metrics?")
+	pflag.String("metrics_port", "9100", "Port of the metrics server")
+	pflag.StringP("cache_strategy", "c", "lru", "Default cache strategy")
+	pflag.Int64("max_cache_bytes", 10, "Max byte size of the cache")
+	pflag.Int("default_replicas", 5, "Replicas of the cache")
+	pflag.Int("weight", 1, "Weight of this node, larger nodes own more keys")
+	pflag.String("peer_selector", "ring", "How keys are allocated to peers: ring, jump or rendezvous")
+	pflag.StringSlice("peers", nil, "Peer addresses, each optionally followed by =weight, discovered from etcd if empty")
+	pflag.Int("max_watches", 10000, "Max hot copies watched on peers, 0 means no limit")
+	pflag.Float64("hot_key_qps", 10, "Requests per second for a remote key to be cached locally, 0 caches every key")
+	pflag.Duration("sweep_interval", 100*time.Millisecond, "How often expired entries are reclaimed, 0 disables sweeping")
+	pflag.Duration("sweep_budget", time.Millisecond, "Time spent reclaiming expired entries at most each interval")
+	pflag.String("snapshot_path", "", "File the caches are saved to and restored from at startup, no snapshots if empty")
+	pflag.Duration("snapshot_interval", time.Minute, "How often the caches are saved, only on shutdown if 0")
+	pflag.Duration("snapshot_max_age", 10*time.Minute, "Snapshots saved longer ago aren't restored, their values may have been set on other nodes since, no limit if 0")
+	pflag.String("aof_path", "", "File Set and Remove are logged to and replayed from at startup, no log if empty")
+	pflag.String("aof_fsync", "everysec", "How often the log is flushed to disk: always, everysec or never")
+	pflag.Int64("aof_rewrite_bytes", 64<<20, "Size after which the log is compacted, once doubled since the last time, never if 0")
+	pflag.String("log_level", "info", "Log level: debug, info, warn or error")
+	pflag.Bool("log_values", false, "Log cached values instead of redacting them")
+	pflag.Parse()
+
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./config")
@@ -20,14 +58,166 @@ func main() {
 	if err != nil {
 		log.Fatal(fmt.Errorf("loading config file error: %s", err))
 	}
+	// flags set on the command line take precedence over the config file
+	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
+		log.Fatal(fmt.Errorf("binding flags failed, err: %s", err))
+	}
 	if err := viper.Unmarshal(config.Config); err != nil {
 		log.Fatal(fmt.Errorf("unmarshaling conf failed, err: %s", err))
 	}
 
-	pflag.StringVarP(&config.Config.Port, "port", "p", "5658", "kache Port")
-	pflag.BoolVarP(&config.Config.Api, "api", "a", true, "Start a api server?")
-	pflag.StringVarP(&config.Config.CacheStrategy, "cache_strategy", "c", "lru", "Default cache strategy")
-	pflag.Int64Var(&config.Config.MaxCacheBytes, "max_cache_bytes", 10, "Max byte size of the cache")
-	pflag.IntVar(&config.Config.DefaultReplicas, "default_replicas", 5, "Replicas of the cache")
-	pflag.Parse()
+	level, err := log.ParseLevel(config.Config.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetLevel(level)
+	logger.SetLogValues(config.Config.LogValues)
+}
+
+func newGetter(group string) kache.Getter {
+	return kache.GetterFunc(func(key string) ([]byte, error) {
+		log.Printf("[SlowDB] search %s/%s", group, key)
+		if v, ok := db[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, fmt.Errorf("%s: %w", key, kache.ErrNotFound)
+	})
+}
+
+func main() {
+	loadConfig()
+
+	self := fmt.Sprintf("%s:%s", config.Config.Addr, config.Config.Port)
+	server, err := kache.NewServer(self)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var aof *kache.AOF
+	if path := config.Config.AOFPath; path != "" {
+		aof, err = kache.OpenAOF(path, config.Config.AOFFsync, config.Config.AOFRewriteBytes)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, g := range config.Config.Groups {
+		opts := []kache.GroupOption{
+			kache.WithCacheBytes(g.CacheBytes),
+			kache.WithPeers(server),
+			kache.WithSweeper(config.Config.SweepInterval, config.Config.SweepBudget),
+		}
+		if g.CacheStrategy != "" {
+			opts = append(opts, kache.WithStrategy(g.CacheStrategy))
+		}
+		if g.MainCacheBytes > 0 {
+			opts = append(opts, kache.WithMainCacheBytes(g.MainCacheBytes))
+		}
+		if g.HotCacheBytes > 0 {
+			opts = append(opts, kache.WithHotCacheBytes(g.HotCacheBytes))
+		}
+		if g.DefaultTTL > 0 {
+			opts = append(opts, kache.WithDefaultTTL(g.DefaultTTL))
+		}
+		if g.NegativeTTL > 0 {
+			opts = append(opts, kache.WithNegativeTTL(g.NegativeTTL))
+		}
+		if g.RefreshAhead > 0 {
+			opts = append(opts, kache.WithRefreshAhead(g.RefreshAhead))
+		}
+		if g.StaleIfError > 0 {
+			opts = append(opts, kache.WithStaleIfError(g.StaleIfError))
+		}
+		if aof != nil {
+			opts = append(opts, kache.WithAOF(aof))
+		}
+		if _, err := kache.NewGroupWithOptions(g.Name, newGetter(g.Name), opts...); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// a restarted node serves what it held instead of hitting the db for everything
+	stopSnapshotter := func() {}
+	if path := config.Config.SnapshotPath; path != "" {
+		var since time.Time
+		if maxAge := config.Config.SnapshotMaxAge; maxAge > 0 {
+			since = time.Now().Add(-maxAge)
+		}
+		// the removes compacted out of the log must not come back
+		if aof != nil && aof.RewrittenAt().After(since) {
+			since = aof.RewrittenAt()
+		}
+		n, err := kache.LoadSnapshot(path, since)
+		if err != nil {
+			log.Errorf("[%s] restoring snapshot %s: %v", self, path, err)
+		} else {
+			log.Printf("[%s] restored %d entries from %s", self, n, path)
+		}
+		stopSnapshotter = kache.StartSnapshotter(path, config.Config.SnapshotInterval)
+	}
+	// the log is newer than the snapshot, its writes win
+	if aof != nil {
+		n, err := aof.Replay()
+		if err != nil {
+			log.Fatalf("[%s] replaying %s: %v", self, config.Config.AOFPath, err)
+		}
+		log.Printf("[%s] replayed %d writes from %s", self, n, config.Config.AOFPath)
+	}
+
+	// without a fixed peer list, the server follows the nodes registered in etcd
+	if len(config.Config.Peers) > 0 {
+		weights, err := kache.ParsePeers(config.Config.Peers...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := server.SetWeightedPeers(weights); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	go func() {
+		if err := server.Start(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	var api *kache.APIServer
+	if config.Config.Api {
+		api = kache.NewAPIServer(fmt.Sprintf("%s:%s", config.Config.Addr, config.Config.ApiPort))
+		go func() {
+			if err := api.Start(); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	var metrics *kache.MetricsServer
+	if config.Config.Metrics {
+		metrics = kache.NewMetricsServer(fmt.Sprintf("%s:%s", config.Config.Addr, config.Config.MetricsPort), server)
+		go func() {
+			if err := metrics.Start(); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	log.Printf("[%s] received %s, shutting down", self, <-sig)
+
+	if api != nil {
+		if err := api.Stop(); err != nil {
+			log.Errorf("[%s] stopping api server: %v", self, err)
+		}
+	}
+	if metrics != nil {
+		if err := metrics.Stop(); err != nil {
+			log.Errorf("[%s] stopping metrics server: %v", self, err)
+		}
+	}
+	stopSnapshotter()
+	server.Stop()
+	if aof != nil {
+		if err := aof.Close(); err != nil {
+			log.Errorf("[%s] closing %s: %v", self, config.Config.AOFPath, err)
+		}
+	}
 }
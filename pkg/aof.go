@@ -0,0 +1,453 @@
+package kache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/falldio/Kache/pkg/logger"
+	pb "github.com/falldio/Kache/pkg/proto"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// how often an AOF is flushed to disk
+const (
+	FSYNC_ALWAYS   = "always"   // after every write, nothing is lost
+	FSYNC_EVERYSEC = "everysec" // once a second, a crash of the machine loses a second of writes
+	FSYNC_NEVER    = "never"    // when the OS decides, a crash of the process loses nothing
+)
+
+// An AOF file starts with aofMagic, the version of its format and when it
+// was last rewritten in unix nanoseconds, followed by length-delimited
+// pb.LogRecord messages.
+const (
+	aofMagic     = "KACHEAOF"
+	aofVersion   = 1
+	aofHeaderLen = len(aofMagic) + 4 + 8
+)
+
+// AOF is an append-only log of the values set in and removed from the groups
+// using it, so that they survive a restart of the node. Loaded values are
+// neither logged nor kept by rewrites, they can be loaded again.
+type AOF struct {
+	path            string
+	fsync           string
+	rewriteMinBytes int64 // the log is compacted once this large and twice its size after the last rewrite, never if 0
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64 // bytes in the file
+	baseSize int64 // bytes in the file after the last rewrite
+	dirty    bool  // written since the last fsync
+	closed   bool
+
+	// rewrittenAt is when the records compacted by the last rewrite were
+	// collected, zero if never. The removes before it are no longer logged.
+	rewrittenAt time.Time
+
+	// rewriteBuf holds the records written while the log is rewritten, to
+	// be appended to the rewritten log
+	rewriteBuf *bytes.Buffer
+
+	rewriting atomic.Bool
+	done      chan struct{} // stops the fsync of everysec
+	stopped   chan struct{}
+}
+
+// OpenAOF opens the log at path, or creates it if missing. Replay it once
+// the groups using it are created, before they are written to.
+func OpenAOF(path, fsync string, rewriteMinBytes int64) (*AOF, error) {
+	switch fsync {
+	case FSYNC_ALWAYS, FSYNC_EVERYSEC, FSYNC_NEVER:
+	default:
+		return nil, fmt.Errorf("unknown aof fsync policy: %s", fsync)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening aof: %w", err)
+	}
+	a := &AOF{
+		path:            path,
+		fsync:           fsync,
+		rewriteMinBytes: rewriteMinBytes,
+		f:               f,
+	}
+	if err := a.init(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fsync == FSYNC_EVERYSEC {
+		a.done, a.stopped = make(chan struct{}), make(chan struct{})
+		go a.fsyncEverySecond()
+	}
+	return a, nil
+}
+
+// init writes the header of a new log, or checks the one of an existing log
+func (a *AOF) init() error {
+	st, err := a.f.Stat()
+	if err != nil {
+		return fmt.Errorf("opening aof: %w", err)
+	}
+	if st.Size() == 0 {
+		if _, err := a.f.Write(aofHeader(time.Time{})); err != nil {
+			return fmt.Errorf("writing aof header: %w", err)
+		}
+		if err := a.f.Sync(); err != nil {
+			return fmt.Errorf("syncing aof: %w", err)
+		}
+		a.size, a.baseSize = int64(aofHeaderLen), int64(aofHeaderLen)
+		return nil
+	}
+	header := make([]byte, aofHeaderLen)
+	if _, err := a.f.ReadAt(header, 0); err != nil || string(header[:len(aofMagic)]) != aofMagic {
+		return fmt.Errorf("%s is not an aof", a.path)
+	}
+	if version := binary.BigEndian.Uint32(header[len(aofMagic):]); version != aofVersion {
+		return fmt.Errorf("unsupported aof version %d", version)
+	}
+	a.rewrittenAt = fromUnixNano(int64(binary.BigEndian.Uint64(header[len(aofMagic)+4:])))
+	a.size, a.baseSize = st.Size(), st.Size()
+	return nil
+}
+
+func aofHeader(rewrittenAt time.Time) []byte {
+	header := binary.BigEndian.AppendUint32([]byte(aofMagic), aofVersion)
+	return binary.BigEndian.AppendUint64(header, uint64(unixNano(rewrittenAt)))
+}
+
+// RewrittenAt returns when the log was last compacted, the zero time if it
+// never was. The removes made before are no longer logged, so a snapshot
+// taken before would bring the removed keys back: LoadSnapshot it with
+// RewrittenAt as the lower bound.
+func (a *AOF) RewrittenAt() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rewrittenAt
+}
+
+// Replay applies the writes logged to the groups of the same name, in the
+// order they were made, and returns how many were read. A record cut short
+// by a crash ends the log, and is dropped from the file.
+func (a *AOF) Replay() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r := &countingReader{r: bufio.NewReader(io.NewSectionReader(a.f, int64(aofHeaderLen), a.size-int64(aofHeaderLen)))}
+	n := 0
+	for {
+		rec := &pb.LogRecord{}
+		err := protodelim.UnmarshalFrom(r, rec)
+		if err == io.EOF {
+			return n, nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			good := int64(aofHeaderLen) + r.good
+			logger.Warn("dropping a torn aof record", logger.F("path", a.path), logger.F("offset", good))
+			if err := a.f.Truncate(good); err != nil {
+				return n, fmt.Errorf("truncating aof: %w", err)
+			}
+			a.size, a.baseSize = good, good
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("reading aof record %d: %w", n, err)
+		}
+		r.good = r.n
+		n++
+		if g := GetGroup(rec.GetGroup()); g != nil {
+			g.replay(rec)
+		}
+	}
+}
+
+// countingReader counts the bytes read, and remembers where the last
+// complete record ends
+type countingReader struct {
+	r    *bufio.Reader
+	n    int64
+	good int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// write applies a write to a cache and logs rec, both under the lock of the
+// log so that it records writes in the order the cache sees them
+func (a *AOF) write(rec *pb.LogRecord, apply func()) error {
+	var buf bytes.Buffer
+	if _, err := protodelim.MarshalTo(&buf, rec); err != nil {
+		return fmt.Errorf("encoding aof record: %w", err)
+	}
+
+	a.mu.Lock()
+	apply()
+	if a.closed {
+		a.mu.Unlock()
+		return fmt.Errorf("aof %s is closed", a.path)
+	}
+	n, err := a.f.Write(buf.Bytes())
+	if err != nil {
+		// don't leave a torn record in front of the next ones
+		a.f.Truncate(a.size)
+		a.mu.Unlock()
+		return fmt.Errorf("appending to aof: %w", err)
+	}
+	a.size += int64(n)
+	if a.rewriteBuf != nil {
+		a.rewriteBuf.Write(buf.Bytes())
+	}
+	switch a.fsync {
+	case FSYNC_ALWAYS:
+		err = a.f.Sync()
+	case FSYNC_EVERYSEC:
+		a.dirty = true
+	}
+	rewrite := a.rewriteMinBytes > 0 && a.size >= a.rewriteMinBytes && a.size >= 2*a.baseSize
+	a.mu.Unlock()
+
+	if rewrite && a.rewriting.CompareAndSwap(false, true) {
+		go func() {
+			defer a.rewriting.Store(false)
+			if err := a.rewrite(); err != nil {
+				logger.Error("rewriting aof", logger.F("path", a.path), logger.Err(err))
+			}
+		}()
+	}
+	if err != nil {
+		return fmt.Errorf("syncing aof: %w", err)
+	}
+	return nil
+}
+
+// Rewrite compacts the log into the values set in the mainCache of the
+// groups using it, values loaded by their getter are left out. Writes go on
+// meanwhile, and are appended to the compacted log before it replaces the
+// current one.
+func (a *AOF) Rewrite() error {
+	if !a.rewriting.CompareAndSwap(false, true) {
+		return fmt.Errorf("aof %s is being rewritten", a.path)
+	}
+	defer a.rewriting.Store(false)
+	return a.rewrite()
+}
+
+func (a *AOF) rewrite() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return fmt.Errorf("aof %s is closed", a.path)
+	}
+	// the entries of the caches and the records written from now on add up
+	// to the state of the caches once the rewrite is over
+	rewrittenAt := time.Now()
+	records := a.setRecords()
+	a.rewriteBuf = &bytes.Buffer{}
+	a.mu.Unlock()
+
+	f, size, err := a.writeRecords(rewrittenAt, records)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	buf := a.rewriteBuf
+	a.rewriteBuf = nil
+	if err != nil {
+		return err
+	}
+	if a.closed {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("aof %s is closed", a.path)
+	}
+
+	n, err := f.Write(buf.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), a.path)
+	}
+	if err != nil {
+		// the current log goes on as if nothing happened
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("replacing aof: %w", err)
+	}
+	a.f.Close()
+	a.f, a.size, a.dirty = f, size+int64(n), false
+	a.baseSize = a.size
+	a.rewrittenAt = rewrittenAt
+	return nil
+}
+
+// setRecords returns a SET record for each value set in the groups using a.
+// a.mu must be held.
+func (a *AOF) setRecords() []*pb.LogRecord {
+	all := allGroups()
+	names := make([]string, 0, len(all))
+	for name, g := range all {
+		if g.aof == a {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var records []*pb.LogRecord
+	for _, name := range names {
+		for _, e := range all[name].mainCache.Entries() {
+			if v := e.Value.(ByteView); v.set {
+				records = append(records, setRecord(name, e.Key, v, e.Expire))
+			}
+		}
+	}
+	return records
+}
+
+// writeRecords writes a log of records compacted at rewrittenAt to a
+// temporary file next to a.path,
+// and returns it opened for appending along with its size
+func (a *AOF) writeRecords(rewrittenAt time.Time, records []*pb.LogRecord) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".rewrite-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("creating aof: %w", err)
+	}
+	name := tmp.Name()
+	bw := bufio.NewWriter(tmp)
+	_, err = bw.Write(aofHeader(rewrittenAt))
+	for _, rec := range records {
+		if err != nil {
+			break
+		}
+		_, err = protodelim.MarshalTo(bw, rec)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+		return nil, 0, fmt.Errorf("writing aof: %w", err)
+	}
+
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		os.Remove(name)
+		return nil, 0, fmt.Errorf("reopening aof: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		os.Remove(name)
+		return nil, 0, fmt.Errorf("reopening aof: %w", err)
+	}
+	return f, st.Size(), nil
+}
+
+func (a *AOF) fsyncEverySecond() {
+	defer close(a.stopped)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.mu.Lock()
+			f, dirty := a.f, a.dirty
+			a.dirty = false
+			a.mu.Unlock()
+			// writes go on while syncing, the file may even be replaced
+			if err := f.Sync(); dirty && err != nil && !errors.Is(err, os.ErrClosed) {
+				logger.Error("syncing aof", logger.F("path", a.path), logger.Err(err))
+			}
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// Close flushes the log to disk, later writes are no longer logged
+func (a *AOF) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+	if a.done != nil {
+		close(a.done)
+		<-a.stopped
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.f.Sync()
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func setRecord(group, key string, v ByteView, evict time.Time) *pb.LogRecord {
+	return &pb.LogRecord{
+		Op:     pb.LogRecord_SET,
+		Group:  group,
+		Key:    key,
+		Value:  v.bts,
+		Expire: unixNano(v.e),
+		Evict:  unixNano(evict),
+	}
+}
+
+func removeRecord(group, key string) *pb.LogRecord {
+	return &pb.LogRecord{
+		Op:    pb.LogRecord_REMOVE,
+		Group: group,
+		Key:   key,
+	}
+}
+
+// replay applies rec to mainCache, without logging it again
+func (g *Group) replay(rec *pb.LogRecord) {
+	if g.cacheBytes <= 0 {
+		return
+	}
+	key := rec.GetKey()
+	switch rec.GetOp() {
+	case pb.LogRecord_SET:
+		var ttl time.Duration
+		if evict := fromUnixNano(rec.GetEvict()); !evict.IsZero() {
+			if ttl = time.Until(evict); ttl <= 0 {
+				// the value set before has been replaced all the same
+				g.mainCache.Remove(key)
+				return
+			}
+		}
+		g.mainCache.Set(key, ByteView{bts: rec.GetValue(), e: fromUnixNano(rec.GetExpire()), set: true}, ttl)
+		g.fit()
+	case pb.LogRecord_REMOVE:
+		g.mainCache.Remove(key)
+	}
+}
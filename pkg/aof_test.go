@@ -0,0 +1,245 @@
+package kache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newAOFGroup creates the group name logging to a fresh AOF opened at path,
+// as a restarted node would
+func newAOFGroup(t *testing.T, name, path string) (*Group, *AOF) {
+	aof, err := OpenAOF(path, FSYNC_ALWAYS, 0)
+	assert.Nil(t, err)
+	t.Cleanup(func() { aof.Close() })
+	g := NewGroup(name, 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		t.Fatalf("expect %s replayed instead of loaded", key)
+		return nil, nil
+	}), WithAOF(aof))
+	return g, aof
+}
+
+func TestAOFReplay(t *testing.T) {
+	dropGroups(t, "aof")
+	path := filepath.Join(t.TempDir(), "kache.aof")
+	g, aof := newAOFGroup(t, "aof", path)
+	g.Set("Tom", []byte("630"), 0)
+	g.Set("Jack", []byte("589"), time.Hour)
+	g.Set("Sam", []byte("567"), 0)
+	g.Set("Tom", []byte("631"), 0)
+	g.Remove("Sam")
+	g.Set("expiring", []byte("1"), time.Nanosecond)
+	assert.Nil(t, aof.Close())
+
+	g, aof = newAOFGroup(t, "aof", path)
+	n, err := aof.Replay()
+	assert.Nil(t, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, 2, g.mainCache.Len())
+	v, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "631", v.String())
+	v, err = g.Get("Jack")
+	assert.Nil(t, err)
+	assert.Equal(t, "589", v.String())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), v.Expire(), time.Second)
+
+	// writes after a replay are appended to the same log
+	g.Set("Sam", []byte("568"), 0)
+	assert.Nil(t, aof.Close())
+	g, aof = newAOFGroup(t, "aof", path)
+	n, err = aof.Replay()
+	assert.Nil(t, err)
+	assert.Equal(t, 7, n)
+	v, _ = g.Get("Sam")
+	assert.Equal(t, "568", v.String())
+}
+
+func TestAOFTornRecord(t *testing.T) {
+	dropGroups(t, "aof-torn")
+	path := filepath.Join(t.TempDir(), "kache.aof")
+	g, aof := newAOFGroup(t, "aof-torn", path)
+	g.Set("Tom", []byte("630"), 0)
+	g.Set("Jack", []byte("589"), 0)
+	assert.Nil(t, aof.Close())
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(path, data[:len(data)-1], 0o600))
+
+	// the records before the torn one are replayed, and the log goes on
+	// from the end of the last of them
+	g, aof = newAOFGroup(t, "aof-torn", path)
+	n, err := aof.Replay()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, g.mainCache.Has("Tom"))
+	assert.False(t, g.mainCache.Has("Jack"))
+	g.Set("Sam", []byte("567"), 0)
+	assert.Nil(t, aof.Close())
+
+	g, aof = newAOFGroup(t, "aof-torn", path)
+	n, err = aof.Replay()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, g.mainCache.Has("Sam"))
+}
+
+func TestAOFRewrite(t *testing.T) {
+	dropGroups(t, "aof-rewrite")
+	path := filepath.Join(t.TempDir(), "kache.aof")
+	g, aof := newAOFGroup(t, "aof-rewrite", path)
+	for i := 0; i < 100; i++ {
+		g.Set("Tom", []byte("630"), 0)
+		g.Set("Jack", []byte("589"), time.Hour)
+	}
+	g.Set("Sam", []byte("567"), 0)
+	g.Remove("Sam")
+	before, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, aof.Rewrite())
+	after, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Less(t, after.Size(), before.Size()/50)
+	g.Set("Sam", []byte("568"), 0)
+	assert.Nil(t, aof.Close())
+
+	g, aof = newAOFGroup(t, "aof-rewrite", path)
+	n, err := aof.Replay()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	v, _ := g.Get("Tom")
+	assert.Equal(t, "630", v.String())
+	v, _ = g.Get("Jack")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), v.Expire(), time.Second)
+	v, _ = g.Get("Sam")
+	assert.Equal(t, "568", v.String())
+
+	// no temporary file is left behind
+	files, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, files, 1)
+}
+
+func TestAOFRewriteSkipsLoaded(t *testing.T) {
+	dropGroups(t, "aof-loaded")
+	path := filepath.Join(t.TempDir(), "kache.aof")
+	aof, err := OpenAOF(path, FSYNC_ALWAYS, 0)
+	assert.Nil(t, err)
+	g := NewGroup("aof-loaded", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), WithAOF(aof))
+	g.Get("Tom")
+	g.Set("Jack", []byte("589"), 0)
+	assert.True(t, g.mainCache.Has("Tom"))
+	assert.Nil(t, aof.Rewrite())
+	assert.Nil(t, aof.Close())
+
+	g, aof = newAOFGroup(t, "aof-loaded", path)
+	n, err := aof.Replay()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, g.mainCache.Has("Tom"))
+	assert.True(t, g.mainCache.Has("Jack"))
+}
+
+func TestAOFWritesDuringRewrite(t *testing.T) {
+	dropGroups(t, "aof-concurrent")
+	path := filepath.Join(t.TempDir(), "kache.aof")
+	g, aof := newAOFGroup(t, "aof-concurrent", path)
+	for i := 0; i < 50; i++ {
+		g.Set(fmt.Sprintf("key-%d", i), []byte("old"), 0)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			g.Set(fmt.Sprintf("key-%d", i), []byte("new"), 0)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, aof.Rewrite())
+	}
+	<-done
+	assert.Nil(t, aof.Close())
+
+	// no write is lost to a rewrite in between
+	g, aof = newAOFGroup(t, "aof-concurrent", path)
+	_, err := aof.Replay()
+	assert.Nil(t, err)
+	for i := 0; i < 50; i++ {
+		v, _ := g.mainCache.Get(fmt.Sprintf("key-%d", i))
+		assert.Equal(t, "new", v.(ByteView).String())
+	}
+}
+
+func TestAOFRewriteAndSnapshot(t *testing.T) {
+	dropGroups(t, "aof-snapshot")
+	dir := t.TempDir()
+	path, snapshot := filepath.Join(dir, "kache.aof"), filepath.Join(dir, "kache.snapshot")
+	g, aof := newAOFGroup(t, "aof-snapshot", path)
+	g.Set("Tom", []byte("630"), 0)
+	assert.Nil(t, SaveSnapshot(snapshot))
+	g.Remove("Tom")
+	assert.Nil(t, aof.Rewrite())
+	assert.Nil(t, aof.Close())
+
+	// the snapshot predates the rewrite, which has dropped the remove
+	g, aof = newAOFGroup(t, "aof-snapshot", path)
+	assert.False(t, aof.RewrittenAt().IsZero())
+	n, err := LoadSnapshot(snapshot, aof.RewrittenAt())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	_, err = aof.Replay()
+	assert.Nil(t, err)
+	assert.False(t, g.mainCache.Has("Tom"))
+
+	// a snapshot taken after the rewrite is restored
+	g.Set("Jack", []byte("589"), 0)
+	assert.Nil(t, SaveSnapshot(snapshot))
+	assert.Nil(t, aof.Close())
+	g, aof = newAOFGroup(t, "aof-snapshot", path)
+	_, err = LoadSnapshot(snapshot, aof.RewrittenAt())
+	assert.Nil(t, err)
+	assert.True(t, g.mainCache.Has("Jack"))
+	assert.False(t, g.mainCache.Has("Tom"))
+}
+
+func TestAOFAutoRewrite(t *testing.T) {
+	dropGroups(t, "aof-auto")
+	path := filepath.Join(t.TempDir(), "kache.aof")
+	aof, err := OpenAOF(path, FSYNC_NEVER, 1<<10)
+	assert.Nil(t, err)
+	defer aof.Close()
+	g := NewGroup("aof-auto", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}), WithAOF(aof))
+	for i := 0; i < 100; i++ {
+		g.Set("Tom", []byte("630"), 0)
+	}
+	assert.Eventually(t, func() bool {
+		st, err := os.Stat(path)
+		return err == nil && st.Size() < 1<<10
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestOpenAOFErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := OpenAOF(filepath.Join(dir, "kache.aof"), "sometimes", 0)
+	assert.ErrorContains(t, err, "unknown aof fsync policy")
+
+	path := filepath.Join(dir, "bad")
+	assert.Nil(t, os.WriteFile(path, []byte("not an aof"), 0o600))
+	_, err = OpenAOF(path, FSYNC_EVERYSEC, 0)
+	assert.NotNil(t, err)
+
+	aof, err := OpenAOF(filepath.Join(dir, "kache.aof"), FSYNC_EVERYSEC, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, aof.Close())
+	assert.Nil(t, aof.Close())
+	assert.NotNil(t, aof.Rewrite())
+}
@@ -0,0 +1,139 @@
+package kache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/falldio/Kache/pkg/logger"
+)
+
+const defaultAPIPath = "/api"
+
+// APIServer is a plain HTTP frontend of the local groups, so that services
+// without gRPC stubs can use the cache as well.
+//
+//	GET    /api?group=<group>&key=<key>           fetch a value
+//	PUT    /api?group=<group>&key=<key>&ttl=<ttl> store the request body
+//	DELETE /api?group=<group>&key=<key>           remove a value
+type APIServer struct {
+	addr    string // address:port
+	mu      sync.Mutex
+	running bool
+	srv     *http.Server
+}
+
+func NewAPIServer(addr string) *APIServer {
+	return &APIServer{
+		addr: addr,
+	}
+}
+
+func (a *APIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != defaultAPIPath {
+		http.NotFound(w, r)
+		return
+	}
+	groupName, key := r.URL.Query().Get("group"), r.URL.Query().Get("key")
+	g := GetGroup(groupName)
+	if g == nil {
+		http.Error(w, fmt.Sprintf("no such group: %s", groupName), http.StatusNotFound)
+		return
+	}
+	if key == "" {
+		http.Error(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		view, err := g.GetContext(r.Context(), key)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if view.Stale() {
+			w.Header().Set("Warning", `110 - "Response is Stale"`)
+		}
+		w.Write(view.ByteSlice())
+	case http.MethodPut:
+		var ttl time.Duration
+		if s := r.URL.Query().Get("ttl"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid ttl %q: %v", s, err), http.StatusBadRequest)
+				return
+			}
+			ttl = d
+		}
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := g.SetContext(r.Context(), key, value, ttl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := g.RemoveContext(r.Context(), key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Start listens on the api address and serves requests until Stop is called.
+func (a *APIServer) Start() error {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return fmt.Errorf("api server already started")
+	}
+	ln, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		a.mu.Unlock()
+		return fmt.Errorf("starting to listen on %s: %w", a.addr, err)
+	}
+	a.running = true
+	a.srv = &http.Server{Handler: a}
+	srv := a.srv
+	a.mu.Unlock()
+
+	logger.Info("api server is running", logger.F("addr", a.addr))
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("starting to serve: %w", err)
+	}
+	return nil
+}
+
+// Stop gracefully shuts the api server down.
+func (a *APIServer) Stop() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.running {
+		return nil
+	}
+	a.running = false
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return a.srv.Shutdown(ctx)
+}
+
+var _ http.Handler = (*APIServer)(nil)
@@ -0,0 +1,73 @@
+package kache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServerGet(t *testing.T) {
+	NewGroup("api-scores", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if v, ok := db[key]; ok {
+			return []byte(v), nil
+		}
+		if key == "missing" {
+			return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}
+		return nil, fmt.Errorf("%s can't be loaded", key)
+	}))
+	a := NewAPIServer("localhost:9999")
+
+	testCases := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"/api?group=api-scores&key=Tom", http.StatusOK, "630"},
+		{"/api?group=unknown&key=Tom", http.StatusNotFound, ""},
+		{"/api?group=api-scores&key=", http.StatusBadRequest, ""},
+		{"/api?group=api-scores&key=unknown", http.StatusBadGateway, ""},
+		{"/api?group=api-scores&key=missing", http.StatusNotFound, ""},
+		{"/other?group=api-scores&key=Tom", http.StatusNotFound, ""},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		assert.Equal(t, tc.status, w.Code, tc.url)
+		if tc.body != "" {
+			assert.Equal(t, tc.body, w.Body.String(), tc.url)
+		}
+	}
+}
+
+func TestAPIServerPutAndDelete(t *testing.T) {
+	g := NewGroup("api-scores", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("%s does not exist", key)
+	}))
+	a := NewAPIServer("localhost:9999")
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api?group=api-scores&key=Kate&ttl=1m", strings.NewReader("701")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	v, err := g.Get("Kate")
+	assert.Nil(t, err)
+	assert.Equal(t, "701", v.String())
+
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api?group=api-scores&key=Kate&ttl=soon", strings.NewReader("701")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api?group=api-scores&key=Kate", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err = g.Get("Kate")
+	assert.NotNil(t, err)
+
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api?group=api-scores&key=Kate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -1,8 +1,36 @@
 package kache
 
+import "time"
+
 // A ByteView holds an immutable view of bytes
 type ByteView struct {
 	bts []byte
+	e   time.Time // when the bytes expire, zero means never
+
+	// refresh is when a view loaded by the getter is reloaded ahead of e
+	// in the background, zero means never
+	refresh time.Time
+
+	// notFound marks a tombstone, cached for a key the getter has not found
+	notFound bool
+
+	// stale marks a view served past e, because the key failed to load again
+	stale bool
+
+	// set marks a view stored by Set rather than loaded, it is kept when an
+	// AOF is rewritten
+	set bool
+}
+
+// Expire returns when the view expires, the zero time if it never does
+func (bv ByteView) Expire() time.Time {
+	return bv.e
+}
+
+// Stale reports whether the view has expired, and is served only because
+// the key can't be loaded for now
+func (bv ByteView) Stale() bool {
+	return bv.stale
 }
 
 func (bv ByteView) Len() int {
@@ -1,6 +1,9 @@
 package kache
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestByteViewLen(t *testing.T) {
 	bv := ByteView{bts: []byte("hello")}
@@ -29,3 +32,13 @@ func TestByteViewCloneBytes(t *testing.T) {
 		t.Fatal("bad clone bytes")
 	}
 }
+
+func TestByteViewExpire(t *testing.T) {
+	if !(ByteView{bts: []byte("hello")}).Expire().IsZero() {
+		t.Fatal("bad default expire")
+	}
+	e := time.Now().Add(time.Minute)
+	if !(ByteView{bts: []byte("hello"), e: e}).Expire().Equal(e) {
+		t.Fatal("bad expire")
+	}
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,12 +27,62 @@ type Cache interface {
 	Has(key string) bool
 	Bytes() int64
 	Shrink()
+	Stats() Stats
+	// Entries returns the live entries, in the order Restore rebuilds the
+	// cache from
+	Entries() []Entry
+	// Restore adds e unless it has expired, or its key is present already
+	// and thus newer
+	Restore(e Entry)
+}
+
+// Entry is an entry of a cache, as saved and restored
+type Entry struct {
+	Key    string
+	Value  Value
+	Expire time.Time // zero means never
+	Freq   int64     // how often the entry has been used, lfu only
+}
+
+func (e Entry) expired(now time.Time) bool {
+	return !e.Expire.IsZero() && !now.Before(e.Expire)
+}
+
+// Stats are the counters of a cache since it was created
+type Stats struct {
+	Hits        int64 // lookups of keys present
+	Misses      int64 // lookups of keys absent or expired
+	Evictions   int64 // entries removed to make room
+	Expirations int64 // entries removed as expired
+	Bytes       int64 // current size
+	Items       int64 // current entries
 }
 
 type baseCache struct {
-	mu       sync.RWMutex
-	maxBytes int64
-	nbytes   int64 // current size
+	mu        sync.RWMutex
+	maxBytes  int64
+	nbytes    int64                         // current size
+	onEvicted func(key string, value Value) // optional, called when an entry is removed
+	expires   map[string]time.Time          // expire time of the entries with a ttl, sampled by sweep
+
+	// updated under a read lock as well
+	hits, misses, evictions, expirations atomic.Int64
+}
+
+type options struct {
+	onEvicted func(key string, value Value)
+}
+
+// Option configures a cache created by NewDefaultCache
+type Option func(*options)
+
+// WithEvictedFunc makes the cache call fn whenever an entry leaves it, be it
+// evicted, expired or removed. fn runs with the cache locked, so it must not
+// use the cache.
+func WithEvictedFunc(fn func(key string, value Value)) Option {
+	return func(o *options) {
+		o.onEvicted = fn
+	}
 }
 
 type cacheEntry struct {
@@ -40,9 +91,55 @@ type cacheEntry struct {
 	ttl   time.Time
 }
 
-func newBaseCache(maxBytes int64) baseCache {
+func newBaseCache(maxBytes int64, opts ...Option) baseCache {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return baseCache{
-		maxBytes: maxBytes,
+		maxBytes:  maxBytes,
+		onEvicted: o.onEvicted,
+		expires:   make(map[string]time.Time),
+	}
+}
+
+// trackExpire is called by the caches once key is set to expire at e,
+// the zero time if it never does
+func (c *baseCache) trackExpire(key string, e time.Time) {
+	if e.IsZero() {
+		delete(c.expires, key)
+	} else {
+		c.expires[key] = e
+	}
+}
+
+// evicted is called by the caches once an entry has been removed
+func (c *baseCache) evicted(key string, value Value) {
+	delete(c.expires, key)
+	if c.onEvicted != nil {
+		c.onEvicted(key, value)
+	}
+}
+
+// recordLookup counts a lookup of a key, present or not
+func (c *baseCache) recordLookup(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+// stats returns the counters of the cache holding items entries.
+// c.mu must be held.
+func (c *baseCache) stats(items int) Stats {
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Bytes:       c.nbytes,
+		Items:       int64(items),
 	}
 }
 
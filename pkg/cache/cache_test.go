@@ -1,6 +1,12 @@
 package cache
 
-import "testing"
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestBaseCacheBytes(t *testing.T) {
 	c := &baseCache{}
@@ -8,3 +14,146 @@ func TestBaseCacheBytes(t *testing.T) {
 		t.Fatalf("expect 0, got %d", c.Bytes())
 	}
 }
+
+func TestWithEvictedFunc(t *testing.T) {
+	for name, newCache := range map[string]func(maxBytes int64, opts ...Option) Cache{
+		"fifo": func(maxBytes int64, opts ...Option) Cache { return newFIFOCache(maxBytes, opts...) },
+		"lru":  func(maxBytes int64, opts ...Option) Cache { return newLRUCache(maxBytes, opts...) },
+		"lfu":  func(maxBytes int64, opts ...Option) Cache { return newLFUCache(maxBytes, opts...) },
+	} {
+		evicted := make([]string, 0)
+		c := newCache(0, WithEvictedFunc(func(key string, value Value) {
+			evicted = append(evicted, key)
+		}))
+		c.Set("k1", String("v1"), 0)
+		c.Set("k2", String("v2"), 0)
+		c.Set("k3", String("v3"), 0)
+		// overwriting a key is not an eviction
+		c.Set("k1", String("v11"), 0)
+		c.Remove("k2")
+		c.Shrink()
+		c.Set("k4", String("v4"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		c.Has("k4")
+		if len(evicted) != 3 || evicted[0] != "k2" || evicted[2] != "k4" {
+			t.Fatalf("%s: expect k2, a shrunk key and k4 evicted, got %v", name, evicted)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	for name, newCache := range map[string]func(maxBytes int64, opts ...Option) Cache{
+		"fifo": func(maxBytes int64, opts ...Option) Cache { return newFIFOCache(maxBytes, opts...) },
+		"lru":  func(maxBytes int64, opts ...Option) Cache { return newLRUCache(maxBytes, opts...) },
+		"lfu":  func(maxBytes int64, opts ...Option) Cache { return newLFUCache(maxBytes, opts...) },
+	} {
+		c := newCache(0)
+		c.Set("k1", String("v1"), 0)
+		c.Set("k2", String("v2"), 0)
+		c.Set("k3", String("v3"), time.Nanosecond)
+		c.Get("k1")
+		c.Get("k1")
+		c.Get("missing")
+		c.Shrink()
+		time.Sleep(time.Millisecond)
+		c.Get("k3")
+
+		want := Stats{Hits: 2, Misses: 2, Evictions: 1, Expirations: 1, Bytes: 4, Items: 1}
+		if got := c.Stats(); got != want {
+			t.Fatalf("%s: expect %+v, got %+v", name, want, got)
+		}
+	}
+}
+
+func TestEntriesRestore(t *testing.T) {
+	for strategy, want := range map[string][]Entry{
+		// oldest first
+		CACHE_STRATEGY_FIFO: {{Key: "k1", Value: String("v1")}, {Key: "k2", Value: String("v2")}},
+		// least recently used first
+		CACHE_STRATEGY_LRU: {{Key: "k2", Value: String("v2")}, {Key: "k1", Value: String("v1")}},
+		// least frequently used first
+		CACHE_STRATEGY_LFU: {{Key: "k2", Value: String("v2"), Freq: 1}, {Key: "k1", Value: String("v1"), Freq: 2}},
+	} {
+		c, _ := New(strategy, 0)
+		c.Set("k1", String("v1"), 0)
+		c.Set("k2", String("v2"), 0)
+		c.Set("k3", String("v3"), time.Nanosecond)
+		c.Get("k1")
+		time.Sleep(time.Millisecond)
+		if got := c.Entries(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expect %v, got %v", strategy, want, got)
+		}
+
+		restored, _ := New(strategy, 0)
+		restored.Set("k2", String("newer"), 0)
+		for _, e := range c.Entries() {
+			restored.Restore(e)
+		}
+		restored.Restore(Entry{Key: "k3", Value: String("v3"), Expire: time.Now()})
+		if v, _ := restored.Get("k2"); v != String("newer") {
+			t.Fatalf("%s: expect present entries kept, got %v", strategy, v)
+		}
+		if restored.Len() != 2 || restored.Bytes() != 11 {
+			t.Fatalf("%s: expect 2 entries of 11 bytes, got %d of %d", strategy, restored.Len(), restored.Bytes())
+		}
+	}
+}
+
+func TestRestoreOrder(t *testing.T) {
+	for _, strategy := range []string{CACHE_STRATEGY_FIFO, CACHE_STRATEGY_LRU, CACHE_STRATEGY_LFU} {
+		c, _ := New(strategy, 0)
+		for _, k := range []string{"k1", "k2", "k3"} {
+			c.Set(k, String("v"), time.Hour)
+		}
+		c.Get("k1")
+		c.Get("k1")
+		c.Get("k2")
+
+		restored, _ := New(strategy, 0)
+		for _, e := range c.Entries() {
+			restored.Restore(e)
+		}
+		if !reflect.DeepEqual(restored.Entries(), c.Entries()) {
+			t.Fatalf("%s: expect %v, got %v", strategy, c.Entries(), restored.Entries())
+		}
+		// the same entry is evicted first
+		c.Shrink()
+		restored.Shrink()
+		if !reflect.DeepEqual(restored.Entries(), c.Entries()) {
+			t.Fatalf("%s: expect %v after shrinking, got %v", strategy, c.Entries(), restored.Entries())
+		}
+	}
+}
+
+func TestConcurrentExpire(t *testing.T) {
+	for name, newCache := range map[string]func(maxBytes int64, opts ...Option) Cache{
+		"fifo": func(maxBytes int64, opts ...Option) Cache { return newFIFOCache(maxBytes, opts...) },
+		"lru":  func(maxBytes int64, opts ...Option) Cache { return newLRUCache(maxBytes, opts...) },
+		"lfu":  func(maxBytes int64, opts ...Option) Cache { return newLFUCache(maxBytes, opts...) },
+	} {
+		c := newCache(0)
+		for i := 0; i < 1000; i++ {
+			c.Set(fmt.Sprintf("k%d", i), String("v"), time.Nanosecond)
+		}
+		time.Sleep(time.Millisecond)
+		// lookups of expired keys remove them, so they must not share a lock
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				for i := 0; i < 1000; i++ {
+					c.Get(fmt.Sprintf("k%d", i))
+					c.Has(fmt.Sprintf("k%d", i))
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if c.Len() != 0 {
+			t.Fatalf("%s: expect every key expired, got %d left", name, c.Len())
+		}
+	}
+}
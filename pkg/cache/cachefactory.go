@@ -1,24 +1,34 @@
 package cache
 
 import (
+	"fmt"
+
 	"github.com/falldio/Kache/pkg/config"
-	log "github.com/sirupsen/logrus"
+	"github.com/falldio/Kache/pkg/logger"
 )
 
-func NewDefaultCache(isHotCache bool) Cache {
-	defer func() {
-		if err := recover(); err != nil {
-			log.Errorf("panic: %s", err)
-		}
-	}()
-	switch config.Config.CacheStrategy {
+// New returns an empty cache evicting entries with strategy once it holds
+// more than maxBytes, 0 maxBytes means no limit
+func New(strategy string, maxBytes int64, opts ...Option) (Cache, error) {
+	switch strategy {
 	case CACHE_STRATEGY_FIFO:
-		return newFIFOCache(config.Config.MaxCacheBytes)
+		return newFIFOCache(maxBytes, opts...), nil
 	case CACHE_STRATEGY_LRU:
-		return newLRUCache(config.Config.MaxCacheBytes)
+		return newLRUCache(maxBytes, opts...), nil
 	case CACHE_STRATEGY_LFU:
-		return newLFUCache(config.Config.MaxCacheBytes)
+		return newLFUCache(maxBytes, opts...), nil
 	default:
-		panic("unknown cache strategy: " + config.Config.CacheStrategy)
+		return nil, fmt.Errorf("unknown cache strategy: %s", strategy)
+	}
+}
+
+// NewDefaultCache returns a cache with the strategy and size set in
+// config.Config, or nil if the strategy is unknown
+func NewDefaultCache(isHotCache bool, opts ...Option) Cache {
+	c, err := New(config.Config.CacheStrategy, config.Config.MaxCacheBytes, opts...)
+	if err != nil {
+		logger.Error("creating default cache", logger.Err(err))
+		return nil
 	}
+	return c
 }
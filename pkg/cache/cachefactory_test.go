@@ -29,3 +29,30 @@ func TestNewDefaultCache(t *testing.T) {
 		t.Fatalf("expect nil, got %s", reflect.TypeOf(c).String())
 	}
 }
+
+func TestNew(t *testing.T) {
+	for strategy, typ := range map[string]string{
+		CACHE_STRATEGY_FIFO: "*cache.FIFOCache",
+		CACHE_STRATEGY_LRU:  "*cache.LRUCache",
+		CACHE_STRATEGY_LFU:  "*cache.LFUCache",
+	} {
+		c, err := New(strategy, 10)
+		if err != nil {
+			t.Fatalf("creating %s cache: %v", strategy, err)
+		}
+		if reflect.TypeOf(c).String() != typ {
+			t.Fatalf("expect %s, got %s", typ, reflect.TypeOf(c).String())
+		}
+		// the limit is the one given rather than the configured one
+		c.Set("k1", String("v1"), 0)
+		c.Set("k2", String("v2"), 0)
+		c.Set("k3", String("v3"), 0)
+		c.Set("k4", String("v4"), 0)
+		if c.Len() == 4 {
+			t.Fatalf("%s: expect entries to be evicted beyond 10 bytes", strategy)
+		}
+	}
+	if _, err := New("unknown", 10); err == nil {
+		t.Fatalf("expect an error for an unknown strategy")
+	}
+}
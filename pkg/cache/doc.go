@@ -1,3 +1,6 @@
 // Package cache provides cache strategy support of the kv system,
 // currently we have fifo, lru (default), lfu ...
+//
+// Expired entries are dropped once looked up, or in the background by
+// StartSweeper before they take the place of live entries.
 package cache
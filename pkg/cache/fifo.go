@@ -4,7 +4,7 @@ import (
 	"container/list"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/falldio/Kache/pkg/logger"
 )
 
 type FIFOCache struct {
@@ -30,26 +30,30 @@ func newFIFOEntry(key string, value Value, ttl time.Duration) *fifoEntry {
 	return e
 }
 
-func newFIFOCache(maxBytes int64) *FIFOCache {
+func newFIFOCache(maxBytes int64, opts ...Option) *FIFOCache {
 	return &FIFOCache{
-		baseCache: newBaseCache(maxBytes),
+		baseCache: newBaseCache(maxBytes, opts...),
 		items:     make(map[string]*list.Element),
 		ll:        list.New(),
 	}
 }
 
 func (c *FIFOCache) Get(key string) (value Value, ok bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer func() { c.recordLookup(ok) }()
 	if v, ok := c.items[key]; ok {
 		ev := v.Value.(*fifoEntry)
 		if !ev.ttl.IsZero() && ev.ttl.Before(time.Now()) {
 			c.remove(v)
+			c.expirations.Add(1)
 			return nil, false
 		}
 		return v.Value.(*fifoEntry).value, true
 	}
-	log.Printf("cache miss key: %s", key)
+	if logger.DebugEnabled() {
+		logger.Debug("cache miss", logger.F("key", key))
+	}
 	return
 }
 
@@ -63,6 +67,7 @@ func (c *FIFOCache) Set(key string, value Value, ttl time.Duration) {
 		c.nbytes += int64(len(key)) + int64(value.Len())
 		c.items[key] = c.ll.PushFront(newFIFOEntry(key, value, ttl))
 	}
+	c.trackExpire(key, c.items[key].Value.(*fifoEntry).ttl)
 	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
 		c.shrink()
 	}
@@ -93,8 +98,8 @@ func (c *FIFOCache) Len() int {
 }
 
 func (c *FIFOCache) Has(key string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	el, ok := c.items[key]
 	if !ok {
 		return false
@@ -102,6 +107,7 @@ func (c *FIFOCache) Has(key string) bool {
 	kv := el.Value.(*fifoEntry)
 	if !kv.ttl.IsZero() && kv.ttl.Before(time.Now()) {
 		c.remove(el)
+		c.expirations.Add(1)
 		return false
 	}
 	return ok
@@ -112,6 +118,7 @@ func (c *FIFOCache) remove(el *list.Element) {
 	c.ll.Remove(el)
 	delete(c.items, kv.key)
 	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	c.evicted(kv.key, kv.value)
 }
 
 func (c *FIFOCache) Shrink() {
@@ -126,6 +133,45 @@ func (c *FIFOCache) shrink() {
 	kv := el.Value.(*fifoEntry)
 	delete(c.items, kv.key)
 	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	c.evicted(kv.key, kv.value)
+	c.evictions.Add(1)
+}
+
+// Entries returns the entries from the first to the last inserted
+func (c *FIFOCache) Entries() []Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	entries := make([]Entry, 0, len(c.items))
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		kv := el.Value.(*fifoEntry)
+		e := Entry{Key: kv.key, Value: kv.value, Expire: kv.ttl}
+		if !e.expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Restore adds e as the last inserted entry
+func (c *FIFOCache) Restore(e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[e.Key]; ok || e.expired(time.Now()) {
+		return
+	}
+	c.items[e.Key] = c.ll.PushFront(&fifoEntry{cacheEntry{key: e.Key, value: e.Value, ttl: e.Expire}})
+	c.nbytes += int64(len(e.Key)) + int64(e.Value.Len())
+	c.trackExpire(e.Key, e.Expire)
+	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
+		c.shrink()
+	}
+}
+
+func (c *FIFOCache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats(len(c.items))
 }
 
 var _ Cache = (*FIFOCache)(nil)
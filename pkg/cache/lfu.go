@@ -3,9 +3,10 @@ package cache
 import (
 	"container/list"
 	"math"
+	"sort"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/falldio/Kache/pkg/logger"
 )
 
 type LFUCache struct {
@@ -37,9 +38,9 @@ func newLFUEntry(key string, value Value, freq int64, ttl time.Duration) *lfuEnt
 	return e
 }
 
-func newLFUCache(maxBytes int64) *LFUCache {
+func newLFUCache(maxBytes int64, opts ...Option) *LFUCache {
 	return &LFUCache{
-		baseCache: newBaseCache(maxBytes),
+		baseCache: newBaseCache(maxBytes, opts...),
 		items:     make(map[string]*list.Element),
 		freqMap:   make(map[int64]*list.List),
 		minFreq:   0,
@@ -50,16 +51,20 @@ func newLFUCache(maxBytes int64) *LFUCache {
 func (c *LFUCache) Get(key string) (v Value, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	defer func() { c.recordLookup(ok) }()
 	if el, ok := c.items[key]; ok {
 		kv := el.Value.(*lfuEntry)
 		if !kv.ttl.IsZero() && kv.ttl.Before(time.Now()) {
 			c.remove(el)
+			c.expirations.Add(1)
 			return nil, false
 		}
 		c.updateFreq(el)
 		return kv.value, true
 	}
-	log.Printf("key %s doesn't hit", key)
+	if logger.DebugEnabled() {
+		logger.Debug("cache miss", logger.F("key", key))
+	}
 	return
 }
 
@@ -90,7 +95,11 @@ func (c *LFUCache) Set(key string, value Value, ttl time.Duration) {
 		c.minFreq = 1
 		c.nbytes += int64(len(key)) + int64(value.Len())
 	}
-	log.Println("set key", key, "value", value, "nbytes", c.nbytes, "maxBytes", c.maxBytes)
+	c.trackExpire(key, c.items[key].Value.(*lfuEntry).ttl)
+	if logger.DebugEnabled() {
+		logger.Debug("cache set", logger.F("key", key), logger.Value("value", value),
+			logger.F("nbytes", c.nbytes), logger.F("maxBytes", c.maxBytes))
+	}
 }
 
 func (c *LFUCache) updateFreq(el *list.Element) {
@@ -118,6 +127,7 @@ func (c *LFUCache) removeLeastFreqUsed() {
 		el = c.freqMap[c.minFreq].Back()
 	}
 	c.remove(el)
+	c.evictions.Add(1)
 }
 
 func (c *LFUCache) Remove(key string) {
@@ -130,12 +140,16 @@ func (c *LFUCache) Remove(key string) {
 func (c *LFUCache) remove(el *list.Element) {
 	if el != nil {
 		kv := el.Value.(*lfuEntry)
-		log.Println("removing key", kv.key, "value", kv.value, "nbytes", c.nbytes, "maxBytes", c.maxBytes)
-		c.freqMap[c.minFreq].Remove(el)
+		if logger.DebugEnabled() {
+			logger.Debug("cache remove", logger.F("key", kv.key), logger.Value("value", kv.value),
+				logger.F("nbytes", c.nbytes), logger.F("maxBytes", c.maxBytes))
+		}
+		c.freqMap[kv.freq].Remove(el)
 		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
 		delete(c.items, kv.key)
-		if c.freqMap[c.minFreq].Len() == 0 {
-			delete(c.freqMap, c.minFreq)
+		c.evicted(kv.key, kv.value)
+		if c.freqMap[kv.freq].Len() == 0 {
+			delete(c.freqMap, kv.freq)
 			min := int64(math.MaxInt64)
 			for f := range c.freqMap {
 				if f < min {
@@ -164,8 +178,8 @@ func (c *LFUCache) Len() int {
 }
 
 func (c *LFUCache) Has(key string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	el, ok := c.items[key]
 	if !ok {
 		return false
@@ -173,6 +187,7 @@ func (c *LFUCache) Has(key string) bool {
 	kv := el.Value.(*lfuEntry)
 	if !kv.ttl.IsZero() && kv.ttl.Before(time.Now()) {
 		c.remove(el)
+		c.expirations.Add(1)
 		return false
 	}
 	c.updateFreq(el)
@@ -185,4 +200,63 @@ func (c *LFUCache) Shrink() {
 	c.removeLeastFreqUsed()
 }
 
+// Entries returns the entries by increasing frequency, and from the least
+// to the most recently used among those of the same frequency
+func (c *LFUCache) Entries() []Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	freqs := make([]int64, 0, len(c.freqMap))
+	for f := range c.freqMap {
+		freqs = append(freqs, f)
+	}
+	sort.Slice(freqs, func(i, j int) bool { return freqs[i] < freqs[j] })
+
+	now := time.Now()
+	entries := make([]Entry, 0, len(c.items))
+	for _, f := range freqs {
+		for el := c.freqMap[f].Back(); el != nil; el = el.Prev() {
+			kv := el.Value.(*lfuEntry)
+			e := Entry{Key: kv.key, Value: kv.value, Expire: kv.ttl, Freq: kv.freq}
+			if !e.expired(now) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries
+}
+
+// Restore adds e with its frequency, 1 if it has none, as the most recently
+// used entry of that frequency
+func (c *LFUCache) Restore(e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[e.Key]; ok || e.expired(time.Now()) {
+		return
+	}
+	freq := e.Freq
+	if freq < 1 {
+		freq = 1
+	}
+	if _, ok := c.freqMap[freq]; !ok {
+		c.freqMap[freq] = list.New()
+	}
+	kv := newLFUEntry(e.Key, e.Value, freq, 0)
+	kv.ttl = e.Expire
+	c.items[e.Key] = c.freqMap[freq].PushFront(kv)
+	if len(c.items) == 1 || freq < c.minFreq {
+		c.minFreq = freq
+	}
+	c.nbytes += int64(len(e.Key)) + int64(e.Value.Len())
+	c.trackExpire(e.Key, e.Expire)
+	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
+		c.removeLeastFreqUsed()
+	}
+}
+
+func (c *LFUCache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats(len(c.items))
+}
+
 var _ Cache = (*LFUCache)(nil)
@@ -63,6 +63,18 @@ func TestRemoveLFU(t *testing.T) {
 	if lfu.Has("key1") {
 		t.Fatalf("lfu shouldn't have key1")
 	}
+
+	// remove key whose freq is not the least one
+	lfu.Set("key1", String("1234"), 0)
+	lfu.Set("key2", String("5678"), 0)
+	lfu.Get("key2")
+	lfu.Remove("key2")
+	if lfu.Has("key2") || lfu.Len() != 1 {
+		t.Fatalf("lfu shouldn't have key2")
+	}
+	if lfu.Bytes() != int64(len("key1")+len("1234")) {
+		t.Fatalf("lfu has wrong bytes, expect: %d, got: %d", len("key1")+len("1234"), lfu.Bytes())
+	}
 }
 
 func TestKeysLFU(t *testing.T) {
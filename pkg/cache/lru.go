@@ -4,7 +4,7 @@ import (
 	"container/list"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/falldio/Kache/pkg/logger"
 )
 
 type LRUCache struct {
@@ -31,9 +31,9 @@ func newLRUEntry(key string, value Value, ttl time.Duration) *lruEntry {
 	return e
 }
 
-func newLRUCache(maxBytes int64) *LRUCache {
+func newLRUCache(maxBytes int64, opts ...Option) *LRUCache {
 	return &LRUCache{
-		baseCache: newBaseCache(maxBytes),
+		baseCache: newBaseCache(maxBytes, opts...),
 		ll:        list.New(),
 		items:     make(map[string]*list.Element),
 	}
@@ -42,10 +42,12 @@ func newLRUCache(maxBytes int64) *LRUCache {
 func (c *LRUCache) Get(key string) (value Value, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	defer func() { c.recordLookup(ok) }()
 	if el, ok := c.items[key]; ok {
 		kv := el.Value.(*lruEntry)
 		if !kv.ttl.IsZero() && kv.ttl.Before(time.Now()) {
 			c.remove(key)
+			c.expirations.Add(1)
 			return nil, false
 		}
 		c.ll.MoveToFront(el)
@@ -56,12 +58,16 @@ func (c *LRUCache) Get(key string) (value Value, ok bool) {
 
 func (c *LRUCache) removeOldest() {
 	el := c.ll.Back()
-	log.Printf("removeOldest: %v", el.Value.(*lruEntry))
 	if el != nil {
 		kv := el.Value.(*lruEntry)
+		if logger.DebugEnabled() {
+			logger.Debug("cache evict", logger.F("key", kv.key), logger.Value("value", kv.value))
+		}
 		delete(c.items, kv.key)
 		c.ll.Remove(el)
 		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+		c.evicted(kv.key, kv.value)
+		c.evictions.Add(1)
 	}
 }
 
@@ -83,6 +89,7 @@ func (c *LRUCache) Set(key string, value Value, ttl time.Duration) {
 		c.items[key] = el
 		c.nbytes += int64(len(key)) + int64(value.Len())
 	}
+	c.trackExpire(key, c.items[key].Value.(*lruEntry).ttl)
 	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
 		c.removeOldest()
 	}
@@ -95,8 +102,8 @@ func (c *LRUCache) Len() int {
 }
 
 func (c *LRUCache) Has(key string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	el, ok := c.items[key]
 	if !ok {
 		return false
@@ -104,6 +111,7 @@ func (c *LRUCache) Has(key string) bool {
 	kv := el.Value.(*lruEntry)
 	if !kv.ttl.IsZero() && kv.ttl.Before(time.Now()) {
 		c.remove(key)
+		c.expirations.Add(1)
 		return false
 	}
 	c.ll.MoveToFront(el)
@@ -132,6 +140,7 @@ func (c *LRUCache) remove(key string) {
 		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
 		delete(c.items, key)
 		c.ll.Remove(el)
+		c.evicted(kv.key, kv.value)
 	}
 }
 
@@ -141,4 +150,41 @@ func (c *LRUCache) Shrink() {
 	c.removeOldest()
 }
 
+// Entries returns the entries from the least to the most recently used
+func (c *LRUCache) Entries() []Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	entries := make([]Entry, 0, len(c.items))
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		kv := el.Value.(*lruEntry)
+		e := Entry{Key: kv.key, Value: kv.value, Expire: kv.ttl}
+		if !e.expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Restore adds e as the most recently used entry
+func (c *LRUCache) Restore(e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[e.Key]; ok || e.expired(time.Now()) {
+		return
+	}
+	c.items[e.Key] = c.ll.PushFront(&lruEntry{cacheEntry{key: e.Key, value: e.Value, ttl: e.Expire}})
+	c.nbytes += int64(len(e.Key)) + int64(e.Value.Len())
+	c.trackExpire(e.Key, e.Expire)
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.removeOldest()
+	}
+}
+
+func (c *LRUCache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats(len(c.items))
+}
+
 var _ Cache = (*LRUCache)(nil)
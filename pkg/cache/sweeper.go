@@ -0,0 +1,114 @@
+package cache
+
+import "time"
+
+const (
+	// entries with a ttl checked by a round of sweeping
+	sweepSample = 20
+	// another round runs right away if more than 1/sweepRepeatRatio of
+	// the sample has expired
+	sweepRepeatRatio = 4
+)
+
+// sweepable is a cache whose expired entries can be reclaimed before they
+// are looked up
+type sweepable interface {
+	// sweep removes the expired entries among up to sample entries with
+	// a ttl, it returns how many were checked and how many removed
+	sweep(sample int, now time.Time) (sampled, expired int)
+}
+
+// StartSweeper reclaims the expired entries of c every interval, like the
+// active expiry of Redis: entries with a ttl are sampled, and sampling goes
+// on while many of them turn out expired, for at most budget per cycle.
+// Call stop to end sweeping.
+func StartSweeper(c Cache, interval, budget time.Duration) (stop func()) {
+	s, ok := c.(sweepable)
+	if !ok || interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sweepCycle(s, budget)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// sweepCycle runs rounds of sweeping until few sampled entries are expired
+// or budget is used up
+func sweepCycle(s sweepable, budget time.Duration) {
+	start := time.Now()
+	for {
+		now := time.Now()
+		sampled, expired := s.sweep(sweepSample, now)
+		if sampled == 0 || expired*sweepRepeatRatio <= sampled || now.Sub(start) >= budget {
+			return
+		}
+	}
+}
+
+// sampleExpired picks up to sample entries with a ttl, at random since maps
+// are iterated in random order, and returns the keys among them expired
+// at now. c.mu must be held.
+func (c *baseCache) sampleExpired(sample int, now time.Time) (sampled int, keys []string) {
+	for key, e := range c.expires {
+		if sampled == sample {
+			break
+		}
+		sampled++
+		if e.Before(now) {
+			keys = append(keys, key)
+		}
+	}
+	return sampled, keys
+}
+
+func (c *LRUCache) sweep(sample int, now time.Time) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sampled, keys := c.sampleExpired(sample, now)
+	c.expirations.Add(int64(len(keys)))
+	for _, key := range keys {
+		c.remove(key)
+	}
+	return sampled, len(keys)
+}
+
+func (c *FIFOCache) sweep(sample int, now time.Time) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sampled, keys := c.sampleExpired(sample, now)
+	c.expirations.Add(int64(len(keys)))
+	for _, key := range keys {
+		c.remove(c.items[key])
+	}
+	return sampled, len(keys)
+}
+
+func (c *LFUCache) sweep(sample int, now time.Time) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sampled, keys := c.sampleExpired(sample, now)
+	c.expirations.Add(int64(len(keys)))
+	for _, key := range keys {
+		c.remove(c.items[key])
+	}
+	return sampled, len(keys)
+}
+
+var (
+	_ sweepable = (*LRUCache)(nil)
+	_ sweepable = (*FIFOCache)(nil)
+	_ sweepable = (*LFUCache)(nil)
+)
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSweep(t *testing.T) {
+	for _, strategy := range []string{CACHE_STRATEGY_FIFO, CACHE_STRATEGY_LRU, CACHE_STRATEGY_LFU} {
+		evicted := 0
+		c, _ := New(strategy, 0, WithEvictedFunc(func(key string, value Value) {
+			evicted++
+		}))
+		for i := 0; i < 100; i++ {
+			c.Set(fmt.Sprintf("expiring%d", i), String("v"), time.Millisecond)
+		}
+		for i := 0; i < 10; i++ {
+			c.Set(fmt.Sprintf("live%d", i), String("v"), time.Hour)
+			c.Set(fmt.Sprintf("forever%d", i), String("v"), 0)
+		}
+		// a key set again without a ttl is not sampled anymore
+		c.Set("expiring0", String("v"), 0)
+		time.Sleep(5 * time.Millisecond)
+
+		// rounds go on while most of the sample is expired
+		sweepCycle(c.(sweepable), time.Second)
+		if c.Len() != 21 || evicted != 99 {
+			t.Fatalf("%s: expect 21 entries left and 99 evicted, got %d and %d", strategy, c.Len(), evicted)
+		}
+		if !c.Has("expiring0") || !c.Has("live0") || !c.Has("forever0") {
+			t.Fatalf("%s: live entries are swept", strategy)
+		}
+		if sampled, _ := c.(sweepable).sweep(sweepSample, time.Now()); sampled != 10 {
+			t.Fatalf("%s: expect only entries with a ttl to be sampled, got %d", strategy, sampled)
+		}
+	}
+}
+
+func TestSweepBudget(t *testing.T) {
+	c := newLRUCache(0)
+	for i := 0; i < 1000; i++ {
+		c.Set(fmt.Sprintf("expiring%d", i), String("v"), time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	// a cycle without budget runs a single round
+	sweepCycle(c, 0)
+	if c.Len() != 1000-sweepSample {
+		t.Fatalf("expect %d entries left, got %d", 1000-sweepSample, c.Len())
+	}
+}
+
+func TestStartSweeper(t *testing.T) {
+	c := newFIFOCache(0)
+	c.Set("k1", String("v1"), time.Millisecond)
+	c.Set("k2", String("v2"), 0)
+	stop := StartSweeper(c, time.Millisecond, time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(time.Second)
+	for c.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expired entry is not swept")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if c.Bytes() != int64(len("k2")+len("v2")) {
+		t.Fatalf("expect bytes of the expired entry reclaimed, got %d", c.Bytes())
+	}
+}
@@ -3,59 +3,286 @@ package kache
 import (
 	"context"
 	"fmt"
-	"log"
+	"sync"
 	"time"
 
 	pb "github.com/falldio/Kache/pkg/proto"
-	"github.com/falldio/Kache/pkg/registry"
-	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+// how long a peer may take to answer when the caller sets no deadline
+const defaultPeerTimeout = 10 * time.Second
+
 type Client struct {
-	// service name: kache/ip:addr
-	name string
+	addr string // address:port of the peer
+
+	mu     sync.Mutex
+	conn   *grpc.ClientConn // dialed on first use, reconnects on its own
+	closed bool
+
+	watchMu sync.Mutex
+	stream  *watchStream // nil unless the peer is being watched
+}
+
+// watchStream is the single invalidation stream from the peer, shared by
+// all the keys watched on it
+type watchStream struct {
+	cancel   context.CancelFunc
+	watchers map[watchKey]map[*watcher]struct{}
 }
 
-func (c *Client) Get(group string, key string) ([]byte, error) {
-	cli, err := clientv3.New(registry.DefaultETCDConfig)
+type watchKey struct {
+	group, key string
+}
+
+type watcher struct {
+	onInvalidated func()
+}
+
+func (c *Client) Get(ctx context.Context, group string, key string) (ByteView, error) {
+	conn, err := c.getConn()
+	if err != nil {
+		return ByteView{}, err
+	}
+	grpcClient := pb.NewKacheClient(conn)
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	resp, err := grpcClient.Get(ctx, &pb.Request{
+		Group: group,
+		Key:   key,
+	})
+	observePeerError(c.addr, "Get", err)
+	if status.Code(err) == codes.NotFound {
+		return ByteView{}, fmt.Errorf("getting %s/%s from peer %s: %w", group, key, c.addr, ErrNotFound)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("creating etcd client: %w", err)
+		return ByteView{}, fmt.Errorf("getting %s/%s from peer %s: %w", group, key, c.addr, err)
+	}
+
+	view := ByteView{bts: resp.GetValue(), stale: resp.GetStale()}
+	if ttl := resp.GetTtl(); ttl != 0 {
+		view.e = time.Now().Add(time.Duration(ttl))
 	}
-	defer cli.Close()
+	return view, nil
+}
 
-	conn, err := registry.ETCDDial(cli, c.name)
+func (c *Client) Set(ctx context.Context, group string, key string, value []byte, ttl time.Duration) error {
+	conn, err := c.getConn()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer conn.Close()
 	grpcClient := pb.NewKacheClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
-	resp, err := grpcClient.Get(ctx, &pb.Request{
+	_, err = grpcClient.Set(ctx, &pb.SetRequest{
 		Group: group,
 		Key:   key,
+		Value: value,
+		Ttl:   int64(ttl),
 	})
+	observePeerError(c.addr, "Set", err)
 	if err != nil {
-		return nil, fmt.Errorf("getting %s/%s from peer %s: %w", group, key, c.name, err)
+		return fmt.Errorf("setting %s/%s on peer %s: %w", group, key, c.addr, err)
 	}
+	return nil
+}
 
-	return resp.GetValue(), nil
+func (c *Client) Delete(ctx context.Context, group string, key string) error {
+	conn, err := c.getConn()
+	if err != nil {
+		return err
+	}
+	grpcClient := pb.NewKacheClient(conn)
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	_, err = grpcClient.Delete(ctx, &pb.Request{
+		Group: group,
+		Key:   key,
+	})
+	observePeerError(c.addr, "Delete", err)
+	if err != nil {
+		return fmt.Errorf("deleting %s/%s on peer %s: %w", group, key, c.addr, err)
+	}
+	return nil
 }
 
-func NewClient(service string) *Client {
-	return &Client{name: service}
+// Stats returns the counters of group on the peer, or of every group of the
+// peer if group is empty, by name
+func (c *Client) Stats(ctx context.Context, group string) (map[string]Stats, error) {
+	conn, err := c.getConn()
+	if err != nil {
+		return nil, err
+	}
+	grpcClient := pb.NewKacheClient(conn)
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	resp, err := grpcClient.Stats(ctx, &pb.StatsRequest{
+		Group: group,
+	})
+	observePeerError(c.addr, "Stats", err)
+	if err != nil {
+		return nil, fmt.Errorf("getting stats of %q from peer %s: %w", group, c.addr, err)
+	}
+	stats := make(map[string]Stats, len(resp.GetGroups()))
+	for _, s := range resp.GetGroups() {
+		stats[s.GetGroup()] = statsFromProto(s)
+	}
+	return stats, nil
+}
+
+// withDefaultTimeout bounds ctx by defaultPeerTimeout unless it has a deadline
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, defaultPeerTimeout)
+}
+
+func NewClient(addr string) *Client {
+	return &Client{addr: addr}
+}
+
+// getConn returns the connection shared by all the calls to the peer
+func (c *Client) getConn() (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, fmt.Errorf("client of peer %s is closed", c.addr)
+	}
+	if c.conn == nil {
+		// dial without blocking, the connection is set up in the background
+		// and re-established whenever it breaks
+		conn, err := grpc.Dial(c.addr,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithChainUnaryInterceptor(traceClientUnary),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("dialing peer %s: %w", c.addr, err)
+		}
+		c.conn = conn
+	}
+	return c.conn, nil
+}
+
+// Close releases the connection to the peer, the client is not usable afterwards
+func (c *Client) Close() error {
+	c.watchMu.Lock()
+	if c.stream != nil {
+		c.stream.cancel()
+	}
+	c.watchMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
 }
 
-func (c *Client) Watch(group string, key string, onUpdated func([]byte)) {
-	cli, err := clientv3.New(registry.DefaultETCDConfig)
+// Watch calls onInvalidated once group/key changes on the peer. The stream
+// of changes is set up by the first call, ctx bounds how long it may take.
+func (c *Client) Watch(ctx context.Context, group string, key string, onInvalidated func()) (cancel func(), err error) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+
+	if c.stream == nil {
+		if err := c.startWatchLocked(ctx); err != nil {
+			observePeerError(c.addr, "Watch", err)
+			return nil, fmt.Errorf("watching %s/%s on peer %s: %w", group, key, c.addr, err)
+		}
+	}
+	ws, wk, w := c.stream, watchKey{group, key}, &watcher{onInvalidated}
+	if ws.watchers[wk] == nil {
+		ws.watchers[wk] = make(map[*watcher]struct{})
+	}
+	ws.watchers[wk][w] = struct{}{}
+	return func() {
+		c.watchMu.Lock()
+		defer c.watchMu.Unlock()
+		delete(ws.watchers[wk], w)
+		if len(ws.watchers[wk]) == 0 {
+			delete(ws.watchers, wk)
+		}
+	}, nil
+}
+
+// startWatchLocked opens the invalidation stream and waits until the peer
+// has subscribed this client, so that no change made afterwards is missed.
+// c.watchMu must be held.
+func (c *Client) startWatchLocked(ctx context.Context) error {
+	conn, err := c.getConn()
 	if err != nil {
-		log.Fatalf("creating etcd client: %v", err)
+		return err
+	}
+	// the stream outlives ctx, which only bounds the subscription
+	streamCtx, cancel := context.WithCancel(context.Background())
+	ctx, cancelWait := withDefaultTimeout(ctx)
+	defer cancelWait()
+	stopWait := context.AfterFunc(ctx, cancel)
+
+	stream, err := pb.NewKacheClient(conn).Watch(streamCtx, &pb.WatchRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if !stopWait() {
+		err = ctx.Err()
 	}
-	defer cli.Close()
-	rch := cli.Watch(context.Background(), fmt.Sprintf("/%s/%s", group, key))
-	for wresp := range rch {
-		for _, ev := range wresp.Events {
-			onUpdated(ev.Kv.Value)
+	if err != nil {
+		cancel()
+		return err
+	}
+	c.stream = &watchStream{
+		cancel:   cancel,
+		watchers: make(map[watchKey]map[*watcher]struct{}),
+	}
+	go c.receive(stream, c.stream)
+	return nil
+}
+
+// receive fires the watchers of the keys changed on the peer, all of them
+// once the stream breaks since changes may have been missed
+func (c *Client) receive(stream pb.Kache_WatchClient, ws *watchStream) {
+	for {
+		batch, err := stream.Recv()
+		fired := make([]*watcher, 0)
+
+		c.watchMu.Lock()
+		if err != nil {
+			for _, set := range ws.watchers {
+				for w := range set {
+					fired = append(fired, w)
+				}
+			}
+			ws.watchers = nil
+			ws.cancel()
+			if c.stream == ws {
+				c.stream = nil
+			}
+		} else {
+			for _, item := range batch.GetItems() {
+				wk := watchKey{item.GetGroup(), item.GetKey()}
+				for w := range ws.watchers[wk] {
+					fired = append(fired, w)
+				}
+				delete(ws.watchers, wk)
+			}
+		}
+		c.watchMu.Unlock()
+
+		for _, w := range fired {
+			w.onInvalidated()
+		}
+		if err != nil {
+			return
 		}
 	}
 }
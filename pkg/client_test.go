@@ -0,0 +1,185 @@
+package kache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startPeer serves the local groups over gRPC on a random port
+func startPeer(t testing.TB) string {
+	addr, _ := startPeerServer(t)
+	return addr
+}
+
+// startPeerServer is like startPeer, but also returns the Server answering
+// the requests
+func startPeerServer(t testing.TB) (string, *Server) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	s, err := NewServer(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("creating server: %v", err)
+	}
+	grpcServer := s.newGRPCServer()
+	go grpcServer.Serve(ln)
+	t.Cleanup(grpcServer.Stop)
+	return ln.Addr().String(), s
+}
+
+func TestClientGet(t *testing.T) {
+	NewGroup("client-scores", 2<<10, mockGetter)
+	c := NewClient(startPeer(t))
+	defer c.Close()
+
+	v, err := c.Get(context.Background(), "client-scores", "Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "Tom", v.String())
+
+	// the connection is kept for later calls
+	conn := c.conn
+	_, err = c.Get(context.Background(), "client-scores", "Jack")
+	assert.Nil(t, err)
+	assert.Same(t, conn, c.conn)
+
+	_, err = c.Get(context.Background(), "unknown", "Tom")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, c.Close())
+	_, err = c.Get(context.Background(), "client-scores", "Tom")
+	assert.NotNil(t, err)
+}
+
+func TestClientSetAndDelete(t *testing.T) {
+	g := NewGroup("client-writes", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}))
+	c := NewClient(startPeer(t))
+	defer c.Close()
+
+	assert.Nil(t, c.Set(context.Background(), "client-writes", "Tom", []byte("630"), time.Minute))
+	v, err := c.Get(context.Background(), "client-writes", "Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "630", v.String())
+	// the copy of the peer expires with the one of the owner
+	assert.WithinDuration(t, time.Now().Add(time.Minute), v.Expire(), time.Second)
+
+	assert.Nil(t, c.Delete(context.Background(), "client-writes", "Tom"))
+	assert.False(t, g.mainCache.Has("Tom"))
+	// a miss of the peer is told apart from a failure
+	_, err = c.Get(context.Background(), "client-writes", "Tom")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = c.Get(context.Background(), "unknown", "Tom")
+	assert.NotNil(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	assert.NotNil(t, c.Set(context.Background(), "unknown", "Tom", []byte("630"), 0))
+	assert.NotNil(t, c.Delete(context.Background(), "client-writes", ""))
+}
+
+func TestClientGetDeadline(t *testing.T) {
+	cancelled := make(chan struct{})
+	NewGroup("client-slow", 2<<10, GetterWithContextFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		}))
+	c := NewClient(startPeer(t))
+	defer c.Close()
+
+	// the deadline of the caller reaches the getter of the peer
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.Get(ctx, "client-slow", "Tom")
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(errors.Unwrap(err)))
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("getter of the peer is not cancelled")
+	}
+}
+
+func TestClientWatch(t *testing.T) {
+	g := NewGroup("client-watch", 2<<10, mockGetter)
+	addr, s := startPeerServer(t)
+	g.RegisterPeers(s)
+	c := NewClient(addr)
+	defer c.Close()
+
+	invalidated := make(chan string, 3)
+	watch := func(key string) func() {
+		cancel, err := c.Watch(context.Background(), "client-watch", key, func() {
+			invalidated <- key
+		})
+		assert.Nil(t, err)
+		return cancel
+	}
+	watch("Tom")
+	watch("Jack")
+	cancel := watch("Sam")
+	cancel()
+
+	// changes on the peer reach the watchers of the changed keys only
+	assert.Nil(t, c.Set(context.Background(), "client-watch", "Tom", []byte("630"), 0))
+	assert.Nil(t, c.Delete(context.Background(), "client-watch", "Sam"))
+	select {
+	case key := <-invalidated:
+		assert.Equal(t, "Tom", key)
+	case <-time.After(time.Second):
+		t.Fatalf("watcher of Tom is not called")
+	}
+
+	// watchers are called once, and all of them once the peer goes away
+	assert.Nil(t, c.Set(context.Background(), "client-watch", "Tom", []byte("631"), 0))
+	s.invalidator.close()
+	select {
+	case key := <-invalidated:
+		assert.Equal(t, "Jack", key)
+	case <-time.After(time.Second):
+		t.Fatalf("watcher of Jack is not called")
+	}
+	select {
+	case key := <-invalidated:
+		t.Fatalf("watcher of %s is called again", key)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClientGetStale(t *testing.T) {
+	g := NewGroup("client-stale", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("unavailable")
+	}), WithStaleIfError(time.Minute))
+	g.mainCache.Set("Tom", ByteView{bts: []byte("630"), e: time.Now().Add(-time.Second)}, time.Minute)
+	c := NewClient(startPeer(t))
+	defer c.Close()
+
+	// the staleness of the value reaches the caller
+	v, err := c.Get(context.Background(), "client-stale", "Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "630", v.String())
+	assert.True(t, v.Stale())
+}
+
+func BenchmarkClientGet(b *testing.B) {
+	g := NewGroup("client-scores", 2<<10, mockGetter)
+	g.Set("Tom", []byte("630"), 0)
+	c := NewClient(startPeer(b))
+	defer c.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get(context.Background(), "client-scores", "Tom"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
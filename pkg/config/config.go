@@ -1,21 +1,70 @@
 package config
 
+import "time"
+
 // Config is the global config object of kache
 type config struct {
-	Port            string
-	Addr            string
-	Api             bool
-	CacheStrategy   string
-	MaxCacheBytes   int64
-	DefaultReplicas int
+	Port             string        `mapstructure:"port"`
+	Addr             string        `mapstructure:"addr"`
+	Api              bool          `mapstructure:"api"`
+	ApiPort          string        `mapstructure:"api_port"`
+	Metrics          bool          `mapstructure:"metrics"` // serve Prometheus metrics on /metrics
+	MetricsPort      string        `mapstructure:"metrics_port"`
+	CacheStrategy    string        `mapstructure:"cache_strategy"`
+	MaxCacheBytes    int64         `mapstructure:"max_cache_bytes"` // size of caches made by cache.NewDefaultCache, groups size their own
+	DefaultReplicas  int           `mapstructure:"default_replicas"`
+	Weight           int           `mapstructure:"weight"`            // virtual nodes of this node are DefaultReplicas*Weight
+	PeerSelector     string        `mapstructure:"peer_selector"`     // ring (default), jump or rendezvous, jump remaps more keys when a node leaves
+	Peers            []string      `mapstructure:"peers"`             // peer addresses (addr:port, or addr:port=weight), discovered from etcd if empty
+	MaxWatches       int           `mapstructure:"max_watches"`       // hot copies watched on peers at most, 0 means no limit
+	HotKeyQPS        float64       `mapstructure:"hot_key_qps"`       // requests per second for a remote key to be kept in hotCache, 0 keeps every key
+	SweepInterval    time.Duration `mapstructure:"sweep_interval"`    // how often expired entries are reclaimed, 0 disables sweeping
+	SweepBudget      time.Duration `mapstructure:"sweep_budget"`      // time spent reclaiming at most each interval
+	SnapshotPath     string        `mapstructure:"snapshot_path"`     // file the caches are saved to and restored from, no snapshots if empty
+	SnapshotInterval time.Duration `mapstructure:"snapshot_interval"` // how often the caches are saved, only on shutdown if 0
+	SnapshotMaxAge   time.Duration `mapstructure:"snapshot_max_age"`  // older snapshots aren't restored, their values may have been set elsewhere since, no limit if 0
+	AOFPath          string        `mapstructure:"aof_path"`          // file Set and Remove are logged to and replayed from, no log if empty
+	AOFFsync         string        `mapstructure:"aof_fsync"`         // always, everysec (default) or never
+	AOFRewriteBytes  int64         `mapstructure:"aof_rewrite_bytes"` // size after which the log is compacted, once doubled since the last time, never if 0
+	LogLevel         string        `mapstructure:"log_level"`         // debug, info (default), warn or error
+	LogValues        bool          `mapstructure:"log_values"`        // log cached values instead of redacting them
+	Groups           []group       `mapstructure:"groups"`            // groups created at startup
+}
+
+// group describes a cache group created when the node boots
+type group struct {
+	Name           string        `mapstructure:"name"`
+	CacheBytes     int64         `mapstructure:"cache_bytes"`
+	CacheStrategy  string        `mapstructure:"cache_strategy"`   // CacheStrategy if empty
+	MainCacheBytes int64         `mapstructure:"main_cache_bytes"` // CacheBytes if 0
+	HotCacheBytes  int64         `mapstructure:"hot_cache_bytes"`  // CacheBytes if 0
+	DefaultTTL     time.Duration `mapstructure:"default_ttl"`      // how long loaded values live, forever if 0
+	NegativeTTL    time.Duration `mapstructure:"negative_ttl"`     // how long keys not found are remembered, not at all if 0
+	RefreshAhead   float64       `mapstructure:"refresh_ahead"`    // part of the ttl after which values are reloaded in the background, never if 0
+	StaleIfError   time.Duration `mapstructure:"stale_if_error"`   // how long expired values are served when loads fail, not at all if 0
 }
 
 var Config *config
 
 func init() {
 	Config = &config{
-		CacheStrategy:   "lru",
-		MaxCacheBytes:   200,
-		DefaultReplicas: 5,
+		Addr:             "localhost",
+		Port:             "5658",
+		ApiPort:          "9999",
+		MetricsPort:      "9100",
+		CacheStrategy:    "lru",
+		MaxCacheBytes:    200,
+		DefaultReplicas:  5,
+		Weight:           1,
+		PeerSelector:     "ring",
+		MaxWatches:       10000,
+		HotKeyQPS:        10,
+		SweepInterval:    100 * time.Millisecond,
+		SweepBudget:      time.Millisecond,
+		SnapshotInterval: time.Minute,
+		SnapshotMaxAge:   10 * time.Minute,
+		AOFFsync:         "everysec",
+		AOFRewriteBytes:  64 << 20,
+		LogLevel:         "info",
 	}
 }
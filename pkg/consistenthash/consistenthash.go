@@ -13,6 +13,7 @@ type Map struct {
 	replicas int
 	keys     []int // sorted
 	hashMap  map[int]string
+	weights  map[string]int // key -> weight, a key owns replicas*weight virtual nodes
 }
 
 func New(replicas int, fn Hash) *Map {
@@ -20,6 +21,7 @@ func New(replicas int, fn Hash) *Map {
 		replicas: replicas,
 		hash:     fn,
 		hashMap:  make(map[int]string),
+		weights:  make(map[string]int),
 	}
 
 	if m.hash == nil {
@@ -31,15 +33,68 @@ func New(replicas int, fn Hash) *Map {
 
 func (m *Map) Add(keys ...string) {
 	for _, key := range keys {
-		for i := 0; i < m.replicas; i++ {
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
-			m.keys = append(m.keys, hash)
-			m.hashMap[hash] = key
+		m.add(key, 1)
+	}
+	sort.Ints(m.keys)
+}
+
+// AddWithWeight adds key to the ring with weight times as many virtual nodes
+// as Add does, so that it owns a proportional share of the keys.
+// Keys already in the ring are re-added with the new weight.
+func (m *Map) AddWithWeight(key string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	if w, ok := m.weights[key]; ok {
+		if w == weight {
+			return
 		}
+		m.Remove(key)
 	}
+	m.add(key, weight)
 	sort.Ints(m.keys)
 }
 
+func (m *Map) add(key string, weight int) {
+	for i := 0; i < m.replicas*weight; i++ {
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		m.keys = append(m.keys, hash)
+		m.hashMap[hash] = key
+	}
+	m.weights[key] = weight
+}
+
+// Remove drops keys and all of their virtual nodes from the ring
+func (m *Map) Remove(keys ...string) {
+	removed := false
+	for _, key := range keys {
+		weight, ok := m.weights[key]
+		if !ok {
+			continue
+		}
+		delete(m.weights, key)
+		for i := 0; i < m.replicas*weight; i++ {
+			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+			// the virtual node may have been taken by another key on hash collision
+			if m.hashMap[hash] == key {
+				delete(m.hashMap, hash)
+				removed = true
+			}
+		}
+	}
+	if !removed {
+		return
+	}
+	// filtering keeps m.keys sorted
+	kept := m.keys[:0]
+	for _, hash := range m.keys {
+		if _, ok := m.hashMap[hash]; ok {
+			kept = append(kept, hash)
+		}
+	}
+	m.keys = kept
+}
+
 func (m *Map) Get(key string) string {
 	if len(m.keys) == 0 {
 		return ""
@@ -1,6 +1,7 @@
 package consistenthash
 
 import (
+	"fmt"
 	"hash/crc32"
 	"sort"
 	"strconv"
@@ -61,3 +62,124 @@ func TestHashing(t *testing.T) {
 		t.Errorf("empty consistent hash should return empty")
 	}
 }
+
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2", "8")
+	hash.Remove("8")
+
+	testCases := map[string]string{
+		"2":  "2",
+		"11": "2",
+		"23": "4",
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("key is %s, expect %v, got %s\n", k, v, hash.Get(k))
+		}
+	}
+	if len(hash.keys) != 9 || len(hash.hashMap) != 9 {
+		t.Errorf("expect 9 virtual nodes, got %d keys and %d hashMap entries", len(hash.keys), len(hash.hashMap))
+	}
+	if !sort.IntsAreSorted(hash.keys) {
+		t.Errorf("keys should be sorted, got %v", hash.keys)
+	}
+
+	// removing unknown nodes changes nothing
+	hash.Remove("10")
+	if len(hash.keys) != 9 {
+		t.Errorf("expect 9 virtual nodes, got %d", len(hash.keys))
+	}
+
+	hash.Remove("6", "4", "2")
+	if hash.Get("2") != "" {
+		t.Errorf("empty consistent hash should return empty")
+	}
+}
+
+func TestRemoveMovesOnlyKeysOfRemovedNode(t *testing.T) {
+	const nodes, keys = 5, 10000
+	hash := New(50, nil)
+	for i := 0; i < nodes; i++ {
+		hash.Add(fmt.Sprintf("10.0.0.%d:8001", i))
+	}
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = hash.Get(fmt.Sprintf("key-%d", i))
+	}
+
+	removed := "10.0.0.3:8001"
+	hash.Remove(removed)
+	moved := 0
+	for i := range before {
+		after := hash.Get(fmt.Sprintf("key-%d", i))
+		if after == removed {
+			t.Fatalf("key-%d is still allocated to the removed node", i)
+		}
+		if after != before[i] {
+			if before[i] != removed {
+				t.Fatalf("key-%d moved from %s to %s, but only keys of %s should move", i, before[i], after, removed)
+			}
+			moved++
+		}
+	}
+	// about 1/N of the keys move
+	if ratio := float64(moved) / keys; ratio < 0.5/nodes || ratio > 2.0/nodes {
+		t.Errorf("expect about %.2f of the keys to move, got %.2f", 1.0/nodes, ratio)
+	}
+}
+
+func TestAddWithWeight(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.AddWithWeight("6", 1)
+	hash.AddWithWeight("4", 2)
+	if len(hash.keys) != 9 {
+		t.Fatalf("expect 9 virtual nodes, got %d", len(hash.keys))
+	}
+	// virtual nodes of 4: 4, 14, 24, 34, 44, 54
+	testCases := map[string]string{
+		"5":  "6",
+		"15": "6",
+		"30": "4",
+		"50": "4",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("key is %s, expect %v, got %s\n", k, v, hash.Get(k))
+		}
+	}
+
+	// re-adding with another weight replaces the virtual nodes
+	hash.AddWithWeight("4", 1)
+	if len(hash.keys) != 6 || hash.Get("25") != "6" {
+		t.Errorf("expect 6 virtual nodes and 25 -> 6, got %d and %s", len(hash.keys), hash.Get("25"))
+	}
+	hash.Remove("4")
+	if len(hash.keys) != 3 || hash.Get("30") != "6" {
+		t.Errorf("expect 3 virtual nodes and 30 -> 6, got %d and %s", len(hash.keys), hash.Get("30"))
+	}
+}
+
+func TestWeightedDistribution(t *testing.T) {
+	const keys = 10000
+	hash := New(50, nil)
+	hash.AddWithWeight("10.0.0.1:8001", 1)
+	hash.AddWithWeight("10.0.0.2:8001", 4)
+	owned := 0
+	for i := 0; i < keys; i++ {
+		if hash.Get(fmt.Sprintf("key-%d", i)) == "10.0.0.2:8001" {
+			owned++
+		}
+	}
+	// the heavier node owns about 4/5 of the keys
+	if ratio := float64(owned) / keys; ratio < 0.65 || ratio > 0.95 {
+		t.Errorf("expect about 0.80 of the keys on the heavier node, got %.2f", ratio)
+	}
+}
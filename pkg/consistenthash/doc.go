@@ -2,4 +2,8 @@
 // from other nodes. Nodes are arranged in a hash ring and kvs are allocated
 // to them accordingly. If the node we are communicating doesn't have the kv we
 // are querying, it automatically fecthes the data from other nodes.
+//
+// Besides the hash ring, keys can be allocated with Jump Consistent Hash or
+// Rendezvous hashing through the PeerSelector interface. Run
+// BenchmarkSelectorBalance to compare how evenly they spread a key space.
 package consistenthash
@@ -0,0 +1,226 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+const (
+	SELECTOR_RING       = "ring"
+	SELECTOR_JUMP       = "jump"
+	SELECTOR_RENDEZVOUS = "rendezvous"
+)
+
+// PeerSelector decides which node a key is allocated to
+type PeerSelector interface {
+	// Add adds nodes with weight 1
+	Add(keys ...string)
+	// AddWithWeight adds a node owning a share of the keys proportional to
+	// weight, or updates the weight of an existing node
+	AddWithWeight(key string, weight int)
+	Remove(keys ...string)
+	// Get returns the node key is allocated to, empty if there is no node
+	Get(key string) string
+}
+
+// NewSelector creates a PeerSelector of the given kind. replicas is the
+// number of virtual nodes per weight of the ring, other kinds ignore it.
+// fn is used to hash keys, nil means the default hash of each kind.
+func NewSelector(kind string, replicas int, fn Hash) (PeerSelector, error) {
+	switch kind {
+	case SELECTOR_RING:
+		return New(replicas, fn), nil
+	case SELECTOR_JUMP:
+		return NewJump(fn), nil
+	case SELECTOR_RENDEZVOUS:
+		return NewRendezvous(fn), nil
+	default:
+		return nil, fmt.Errorf("unknown peer selector: %s", kind)
+	}
+}
+
+// hash64 hashes the concatenation of parts with fn if provided, or with
+// 64-bit FNV-1a. The result is mixed so that similar inputs spread over
+// all 64 bits.
+func hash64(fn Hash, parts ...string) uint64 {
+	var h uint64
+	if fn != nil {
+		var data []byte
+		for _, part := range parts {
+			data = append(data, part...)
+		}
+		h = uint64(fn(data))
+	} else {
+		h = fnvOffset64
+		for _, part := range parts {
+			for i := 0; i < len(part); i++ {
+				h ^= uint64(part[i])
+				h *= fnvPrime64
+			}
+		}
+	}
+	// splitmix64 finalizer
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// Jump allocates keys with Jump Consistent Hash (Lamping & Veach), which
+// needs no virtual nodes and balances keys almost perfectly.
+//
+// Buckets are the nodes in sorted order, a node taking weight buckets,
+// so that every server agrees on them whatever order it saw nodes join and
+// leave in. The price is that only adding or removing the last bucket moves
+// the minimal 1/N of the keys: a change to bucket i shifts the buckets after
+// it, remapping about (N-i)/N of the keys. Pick ring or rendezvous for
+// clusters whose members change often.
+type Jump struct {
+	hash    Hash
+	weights map[string]int
+	buckets []string
+}
+
+func NewJump(fn Hash) *Jump {
+	return &Jump{
+		hash:    fn,
+		weights: make(map[string]int),
+	}
+}
+
+func (j *Jump) Add(keys ...string) {
+	for _, key := range keys {
+		j.weights[key] = 1
+	}
+	j.rebuild()
+}
+
+func (j *Jump) AddWithWeight(key string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	if w, ok := j.weights[key]; ok && w == weight {
+		return
+	}
+	j.weights[key] = weight
+	j.rebuild()
+}
+
+func (j *Jump) Remove(keys ...string) {
+	for _, key := range keys {
+		delete(j.weights, key)
+	}
+	j.rebuild()
+}
+
+func (j *Jump) rebuild() {
+	nodes := make([]string, 0, len(j.weights))
+	for key := range j.weights {
+		nodes = append(nodes, key)
+	}
+	sort.Strings(nodes)
+	j.buckets = j.buckets[:0]
+	for _, node := range nodes {
+		for i := 0; i < j.weights[node]; i++ {
+			j.buckets = append(j.buckets, node)
+		}
+	}
+}
+
+func (j *Jump) Get(key string) string {
+	if len(j.buckets) == 0 {
+		return ""
+	}
+	return j.buckets[jumpHash(hash64(j.hash, key), len(j.buckets))]
+}
+
+// jumpHash maps key to a bucket in [0, buckets)
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// Rendezvous allocates keys with weighted Rendezvous (Highest Random Weight)
+// hashing: every node scores the key and the highest score wins. Removing a
+// node only moves the keys it owned, at the cost of O(N) lookups.
+type Rendezvous struct {
+	hash    Hash
+	weights map[string]int
+	nodes   []string // sorted, so that ties are broken the same way everywhere
+}
+
+func NewRendezvous(fn Hash) *Rendezvous {
+	return &Rendezvous{
+		hash:    fn,
+		weights: make(map[string]int),
+	}
+}
+
+func (r *Rendezvous) Add(keys ...string) {
+	for _, key := range keys {
+		r.weights[key] = 1
+	}
+	r.rebuild()
+}
+
+func (r *Rendezvous) AddWithWeight(key string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	if w, ok := r.weights[key]; ok && w == weight {
+		return
+	}
+	r.weights[key] = weight
+	r.rebuild()
+}
+
+func (r *Rendezvous) Remove(keys ...string) {
+	for _, key := range keys {
+		delete(r.weights, key)
+	}
+	r.rebuild()
+}
+
+func (r *Rendezvous) rebuild() {
+	r.nodes = r.nodes[:0]
+	for key := range r.weights {
+		r.nodes = append(r.nodes, key)
+	}
+	sort.Strings(r.nodes)
+}
+
+func (r *Rendezvous) Get(key string) string {
+	var (
+		owner string
+		best  = math.Inf(-1)
+	)
+	for _, node := range r.nodes {
+		h := hash64(r.hash, node, "/", key)
+		// map the hash into (0, 1), then score = -w / ln(u)
+		u := (float64(h>>11) + 0.5) / (1 << 53)
+		score := -float64(r.weights[node]) / math.Log(u)
+		if score > best {
+			owner, best = node, score
+		}
+	}
+	return owner
+}
+
+var (
+	_ PeerSelector = (*Map)(nil)
+	_ PeerSelector = (*Jump)(nil)
+	_ PeerSelector = (*Rendezvous)(nil)
+)
@@ -0,0 +1,135 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+var selectors = []string{SELECTOR_RING, SELECTOR_JUMP, SELECTOR_RENDEZVOUS}
+
+func newTestSelector(t testing.TB, kind string) PeerSelector {
+	s, err := NewSelector(kind, 50, nil)
+	if err != nil {
+		t.Fatalf("creating %s selector: %v", kind, err)
+	}
+	return s
+}
+
+func TestNewSelector(t *testing.T) {
+	for _, kind := range selectors {
+		s := newTestSelector(t, kind)
+		if s.Get("Tom") != "" {
+			t.Errorf("empty %s selector should return empty", kind)
+		}
+	}
+	if _, err := NewSelector("unknown", 50, nil); err == nil {
+		t.Errorf("expect error on unknown selector")
+	}
+}
+
+func TestSelectorOrderIndependent(t *testing.T) {
+	for _, kind := range selectors {
+		s1, s2 := newTestSelector(t, kind), newTestSelector(t, kind)
+		s1.Add("10.0.0.1:8001", "10.0.0.2:8001", "10.0.0.3:8001")
+		s2.Add("10.0.0.3:8001", "10.0.0.1:8001")
+		s2.Add("10.0.0.2:8001")
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			if s1.Get(key) != s2.Get(key) {
+				t.Fatalf("%s: %s is allocated to %s and %s", kind, key, s1.Get(key), s2.Get(key))
+			}
+		}
+	}
+}
+
+func TestSelectorRemove(t *testing.T) {
+	const nodes, keys = 5, 10000
+	// removing a middle node moves only its keys with ring and rendezvous,
+	// but the keys of every bucket after it with jump
+	const removedIndex = 1
+	maxMoved := map[string]float64{
+		SELECTOR_RING:       1.0/nodes + 0.1,
+		SELECTOR_JUMP:       float64(nodes-removedIndex)/nodes + 0.1,
+		SELECTOR_RENDEZVOUS: 1.0/nodes + 0.1,
+	}
+	for _, kind := range selectors {
+		s := newTestSelector(t, kind)
+		for i := 0; i < nodes; i++ {
+			s.Add(fmt.Sprintf("10.0.0.%d:8001", i))
+		}
+		removed := fmt.Sprintf("10.0.0.%d:8001", removedIndex)
+		before := make([]string, keys)
+		for i := range before {
+			before[i] = s.Get(fmt.Sprintf("key-%d", i))
+		}
+		s.Remove(removed)
+		moved := 0
+		for i := range before {
+			after := s.Get(fmt.Sprintf("key-%d", i))
+			if after == removed {
+				t.Fatalf("%s: key-%d is still allocated to the removed node", kind, i)
+			}
+			if after == before[i] {
+				continue
+			}
+			moved++
+			if before[i] != removed && (kind != SELECTOR_JUMP || before[i] < removed) {
+				t.Fatalf("%s: key-%d moved from %s to %s", kind, i, before[i], after)
+			}
+		}
+		if ratio := float64(moved) / keys; ratio > maxMoved[kind] {
+			t.Errorf("%s: expect at most %.2f of the keys moved, got %.2f", kind, maxMoved[kind], ratio)
+		}
+	}
+}
+
+func TestSelectorWeight(t *testing.T) {
+	const keys = 10000
+	for _, kind := range selectors {
+		s := newTestSelector(t, kind)
+		s.AddWithWeight("10.0.0.1:8001", 1)
+		s.AddWithWeight("10.0.0.2:8001", 4)
+		owned := 0
+		for i := 0; i < keys; i++ {
+			if s.Get(fmt.Sprintf("key-%d", i)) == "10.0.0.2:8001" {
+				owned++
+			}
+		}
+		if ratio := float64(owned) / keys; ratio < 0.65 || ratio > 0.95 {
+			t.Errorf("%s: expect about 0.80 of the keys on the heavier node, got %.2f", kind, ratio)
+		}
+	}
+}
+
+// BenchmarkSelectorBalance reports how evenly each selector spreads keys over
+// 10 nodes: max/mean is the load of the busiest node relative to a perfect
+// split, stddev/mean the coefficient of variation.
+func BenchmarkSelectorBalance(b *testing.B) {
+	const nodes, keys = 10, 100000
+	for _, kind := range selectors {
+		b.Run(kind, func(b *testing.B) {
+			s := newTestSelector(b, kind)
+			for i := 0; i < nodes; i++ {
+				s.Add(fmt.Sprintf("10.0.0.%d:8001", i))
+			}
+			counts := make(map[string]int, nodes)
+			for i := 0; i < keys; i++ {
+				counts[s.Get(fmt.Sprintf("user:%d", i))]++
+			}
+			mean := float64(keys) / nodes
+			var max, variance float64
+			for _, c := range counts {
+				max = math.Max(max, float64(c))
+				variance += (float64(c) - mean) * (float64(c) - mean) / nodes
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Get(fmt.Sprintf("user:%d", i%keys))
+			}
+			b.ReportMetric(max/mean, "max/mean")
+			b.ReportMetric(math.Sqrt(variance)/mean, "stddev/mean")
+		})
+	}
+}
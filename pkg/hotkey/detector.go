@@ -0,0 +1,136 @@
+package hotkey
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// rows of the sketch, each with a hash function of its own
+	depth = 4
+	// counters in a row
+	defaultWidth = 1 << 12
+	// counters are halved once a window
+	defaultWindow = time.Second
+)
+
+// Detector counts requests of keys and reports the keys whose rate is above
+// a threshold. Counts are estimates, they may be overestimated but never
+// underestimated, so a cold key is at worst reported hot when the sketch is
+// crowded.
+type Detector struct {
+	mu        sync.Mutex
+	threshold uint32 // counter a key reaches when requested at qps
+	disabled  bool   // every key is hot
+	window    time.Duration
+	decayed   time.Time // last time counters were halved
+	counters  [depth][]uint32
+
+	now func() time.Time
+}
+
+// NewDetector returns a Detector reporting the keys requested more than qps
+// times a second. A qps of 0 or less reports every key as hot.
+func NewDetector(qps float64) *Detector {
+	d := &Detector{
+		window:   defaultWindow,
+		disabled: qps <= 0,
+		now:      time.Now,
+	}
+	// halving the counters every window makes a key requested at qps settle
+	// at about 2*qps*window
+	threshold := math.Ceil(2 * qps * d.window.Seconds())
+	if threshold > math.MaxUint32 {
+		threshold = math.MaxUint32
+	}
+	d.threshold = uint32(math.Max(threshold, 1))
+	for i := range d.counters {
+		d.counters[i] = make([]uint32, defaultWidth)
+	}
+	d.decayed = d.now()
+	return d
+}
+
+// Touch records a request of key, and reports whether key is hot
+func (d *Detector) Touch(key string) bool {
+	if d.disabled {
+		return true
+	}
+	h1, h2 := hash(key)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.decay()
+	min := uint32(math.MaxUint32)
+	for i := range d.counters {
+		c := &d.counters[i][index(h1, h2, i, len(d.counters[i]))]
+		if *c < math.MaxUint32 {
+			*c++
+		}
+		if *c < min {
+			min = *c
+		}
+	}
+	return min >= d.threshold
+}
+
+// Hot reports whether key is hot without recording a request of it
+func (d *Detector) Hot(key string) bool {
+	return d.Estimate(key) >= d.threshold
+}
+
+// Estimate returns the decayed count of key
+func (d *Detector) Estimate(key string) uint32 {
+	if d.disabled {
+		return math.MaxUint32
+	}
+	h1, h2 := hash(key)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.decay()
+	min := uint32(math.MaxUint32)
+	for i := range d.counters {
+		if c := d.counters[i][index(h1, h2, i, len(d.counters[i]))]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// decay halves the counters once for every window passed since the last
+// time. d.mu must be held.
+func (d *Detector) decay() {
+	elapsed := d.now().Sub(d.decayed)
+	if elapsed < d.window {
+		return
+	}
+	n := uint(elapsed / d.window)
+	d.decayed = d.decayed.Add(time.Duration(n) * d.window)
+	if n > 32 {
+		n = 32
+	}
+	for i := range d.counters {
+		for j := range d.counters[i] {
+			d.counters[i][j] >>= n
+		}
+	}
+}
+
+// hash returns two independent 32-bit hashes of key, from which the hash
+// functions of all the rows are derived (Kirsch-Mitzenmacher)
+func hash(key string) (uint32, uint32) {
+	// FNV-1a, inlined so that touching a key doesn't allocate
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	// an odd h2 never maps two rows of a key to the same counter
+	return uint32(h), uint32(h>>32) | 1
+}
+
+func index(h1, h2 uint32, row, width int) int {
+	return int((h1 + uint32(row)*h2) % uint32(width))
+}
@@ -0,0 +1,109 @@
+package hotkey
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestDetector(qps float64) (*Detector, *clock) {
+	d := NewDetector(qps)
+	c := &clock{t: time.Now()}
+	d.now = c.now
+	d.decayed = c.t
+	return d, c
+}
+
+func TestTouch(t *testing.T) {
+	d, _ := newTestDetector(5)
+	// a key turns hot once it is requested 2*qps times within a window
+	for i := 0; i < 9; i++ {
+		if d.Touch("Tom") {
+			t.Fatalf("Tom is hot after %d requests", i+1)
+		}
+	}
+	if !d.Touch("Tom") {
+		t.Fatalf("Tom is not hot after 10 requests")
+	}
+	if !d.Hot("Tom") || d.Hot("Jack") {
+		t.Fatalf("expect only Tom to be hot")
+	}
+}
+
+func TestDecay(t *testing.T) {
+	d, c := newTestDetector(5)
+	for i := 0; i < 10; i++ {
+		d.Touch("Tom")
+	}
+	c.t = c.t.Add(time.Second)
+	if got := d.Estimate("Tom"); got != 5 {
+		t.Fatalf("expect 5 after one window, got %d", got)
+	}
+	if d.Hot("Tom") {
+		t.Fatalf("Tom is still hot after cooling down")
+	}
+
+	// a key requested at qps stays hot
+	for i := 0; i < 5; i++ {
+		d.Touch("Tom")
+	}
+	if !d.Hot("Tom") {
+		t.Fatalf("Tom requested at qps is not hot")
+	}
+
+	c.t = c.t.Add(time.Hour)
+	if got := d.Estimate("Tom"); got != 0 {
+		t.Fatalf("expect 0 after an hour, got %d", got)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	d := NewDetector(0)
+	if !d.Touch("Tom") || !d.Hot("Jack") {
+		t.Fatalf("expect every key to be hot")
+	}
+}
+
+func TestOverestimate(t *testing.T) {
+	d, _ := newTestDetector(100)
+	for i := 0; i < 10000; i++ {
+		d.Touch(fmt.Sprintf("cold-%d", i))
+	}
+	for i := 0; i < 200; i++ {
+		d.Touch("Tom")
+	}
+	if !d.Hot("Tom") {
+		t.Fatalf("Tom is not hot")
+	}
+	// keys seen once each stay far below the threshold despite collisions
+	hot := 0
+	for i := 0; i < 10000; i++ {
+		if d.Hot(fmt.Sprintf("cold-%d", i)) {
+			hot++
+		}
+	}
+	if hot > 0 {
+		t.Fatalf("%d cold keys are reported hot", hot)
+	}
+}
+
+func BenchmarkTouch(b *testing.B) {
+	d := NewDetector(100)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Touch(keys[i%len(keys)])
+	}
+}
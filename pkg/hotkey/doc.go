@@ -0,0 +1,5 @@
+// Package hotkey tells which keys are requested often enough to be worth a
+// copy on every node. Request rates are estimated with a Count-Min Sketch
+// whose counters are halved periodically, so memory stays fixed however
+// many keys are seen and keys that cool down are forgotten.
+package hotkey
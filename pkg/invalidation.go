@@ -0,0 +1,120 @@
+package kache
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/falldio/Kache/pkg/proto"
+)
+
+const (
+	// how long a change may wait for others to be sent along with
+	invalidationFlushInterval = 10 * time.Millisecond
+	// changes sent at most in one batch
+	maxInvalidationBatch = 128
+	// batches buffered for a subscriber, which is dropped when it falls behind
+	subscriberBuffer = 64
+)
+
+// invalidator batches the keys changed on this node, and hands the batches
+// to the peers watching this node for them
+type invalidator struct {
+	mu      sync.Mutex
+	closed  bool
+	pending []*pb.Invalidation
+	timer   *time.Timer // flushes pending, nil if nothing is pending
+	subs    map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch chan *pb.InvalidationBatch // closed when the subscriber is dropped
+}
+
+func newInvalidator() *invalidator {
+	return &invalidator{
+		subs: make(map[*subscriber]struct{}),
+	}
+}
+
+// add queues an invalidation of group/key for every subscriber
+func (inv *invalidator) add(group, key string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.closed || len(inv.subs) == 0 {
+		return
+	}
+	inv.pending = append(inv.pending, &pb.Invalidation{Group: group, Key: key})
+	if len(inv.pending) >= maxInvalidationBatch {
+		inv.flushLocked()
+		return
+	}
+	if inv.timer == nil {
+		inv.timer = time.AfterFunc(invalidationFlushInterval, inv.flush)
+	}
+}
+
+func (inv *invalidator) flush() {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.flushLocked()
+}
+
+// inv.mu must be held
+func (inv *invalidator) flushLocked() {
+	if inv.timer != nil {
+		inv.timer.Stop()
+		inv.timer = nil
+	}
+	if len(inv.pending) == 0 {
+		return
+	}
+	batch := &pb.InvalidationBatch{Items: inv.pending}
+	inv.pending = nil
+	for sub := range inv.subs {
+		select {
+		case sub.ch <- batch:
+		default:
+			// the subscriber would miss invalidations, drop it so that it
+			// drops all its copies instead of serving stale ones
+			close(sub.ch)
+			delete(inv.subs, sub)
+		}
+	}
+}
+
+func (inv *invalidator) subscribe() *subscriber {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	sub := &subscriber{ch: make(chan *pb.InvalidationBatch, subscriberBuffer)}
+	if inv.closed {
+		close(sub.ch)
+		return sub
+	}
+	inv.subs[sub] = struct{}{}
+	return sub
+}
+
+func (inv *invalidator) unsubscribe(sub *subscriber) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if _, ok := inv.subs[sub]; ok {
+		close(sub.ch)
+		delete(inv.subs, sub)
+	}
+}
+
+// close sends what is pending and drops every subscriber
+func (inv *invalidator) close() {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.flushLocked()
+	inv.closed = true
+	for sub := range inv.subs {
+		close(sub.ch)
+		delete(inv.subs, sub)
+	}
+}
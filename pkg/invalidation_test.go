@@ -0,0 +1,64 @@
+package kache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidatorBatches(t *testing.T) {
+	inv := newInvalidator()
+	// nothing is queued without subscribers
+	inv.add("scores", "Tom")
+	assert.Empty(t, inv.pending)
+
+	sub := inv.subscribe()
+	inv.add("scores", "Tom")
+	inv.add("scores", "Jack")
+	select {
+	case batch := <-sub.ch:
+		assert.Len(t, batch.GetItems(), 2)
+		assert.Equal(t, "Tom", batch.GetItems()[0].GetKey())
+		assert.Equal(t, "Jack", batch.GetItems()[1].GetKey())
+	case <-time.After(time.Second):
+		t.Fatalf("batch is not flushed")
+	}
+
+	// a full batch is sent right away
+	for i := 0; i < maxInvalidationBatch; i++ {
+		inv.add("scores", fmt.Sprintf("key-%d", i))
+	}
+	select {
+	case batch := <-sub.ch:
+		assert.Len(t, batch.GetItems(), maxInvalidationBatch)
+	default:
+		t.Fatalf("full batch is not sent")
+	}
+
+	inv.unsubscribe(sub)
+	_, ok := <-sub.ch
+	assert.False(t, ok)
+}
+
+func TestInvalidatorDropsSlowSubscriber(t *testing.T) {
+	inv := newInvalidator()
+	slow, fast := inv.subscribe(), inv.subscribe()
+	for i := 0; i <= subscriberBuffer; i++ {
+		inv.add("scores", "Tom")
+		inv.flush()
+		<-fast.ch
+	}
+	for i := 0; i < subscriberBuffer; i++ {
+		<-slow.ch
+	}
+	_, ok := <-slow.ch
+	assert.False(t, ok)
+
+	inv.close()
+	_, ok = <-fast.ch
+	assert.False(t, ok)
+	_, ok = <-inv.subscribe().ch
+	assert.False(t, ok)
+}
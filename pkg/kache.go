@@ -1,15 +1,26 @@
 package kache
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/falldio/Kache/pkg/cache"
+	"github.com/falldio/Kache/pkg/config"
+	"github.com/falldio/Kache/pkg/hotkey"
+	"github.com/falldio/Kache/pkg/logger"
 	"github.com/falldio/Kache/pkg/singleflight"
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrNotFound is returned for keys that don't exist. A getter returns it, or
+// an error wrapping it, so that the miss is cached for the negative TTL of
+// the group instead of asking the getter again on every lookup.
+var ErrNotFound = errors.New("not found")
+
 type Getter interface {
 	Get(key string) ([]byte, error)
 }
@@ -20,6 +31,42 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// GetterWithContext is a Getter that stops loading once ctx is done,
+// a Group calls GetContext instead of Get if its getter implements it.
+type GetterWithContext interface {
+	Getter
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+type GetterWithContextFunc func(ctx context.Context, key string) ([]byte, error)
+
+func (f GetterWithContextFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+func (f GetterWithContextFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// GetterWithTTL is a Getter that also tells how long a value may be
+// cached, a Group calls GetWithTTL instead of Get or GetContext if its getter
+// implements it. A ttl of 0 or less falls back to the default TTL of the group.
+type GetterWithTTL interface {
+	Getter
+	GetWithTTL(ctx context.Context, key string) (value []byte, ttl time.Duration, err error)
+}
+
+type GetterWithTTLFunc func(ctx context.Context, key string) ([]byte, time.Duration, error)
+
+func (f GetterWithTTLFunc) Get(key string) ([]byte, error) {
+	v, _, err := f(context.Background(), key)
+	return v, err
+}
+
+func (f GetterWithTTLFunc) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return f(ctx, key)
+}
+
 type Group struct {
 	name   string
 	getter Getter
@@ -31,13 +78,31 @@ type Group struct {
 	// that we would like to store it on every node, in order to avoid extra netwrok communication.
 	hotCache cache.Cache
 
-	cacheBytes int64 // total bytes limit of mainCache and hotCache
+	// hotKeys tells which keys of other peers are popular enough for hotCache
+	hotKeys *hotkey.Detector
+
+	cacheBytes    int64         // total bytes limit of mainCache and hotCache
+	hotCacheRatio float64       // hotCache is evicted first once larger than mainCache*hotCacheRatio
+	defaultTTL    time.Duration // how long loaded values live, 0 means forever
+	negativeTTL   time.Duration // how long keys not found are remembered, 0 means not at all
+	refreshAhead  float64       // part of the ttl after which a value is reloaded in the background
+	staleIfError  time.Duration // how long expired values are kept to be served when loads fail
+	refreshing    sync.Map      // keys being reloaded in the background
+	stopSweepers  func()        // stops reclaiming expired entries in the background
 
 	peers PeerPicker
 
+	// aof logs the values set in and removed from mainCache, nil if not logged
+	aof *AOF
+
 	// use singleflight.Group to make sure that each key
 	// is only fetched once
 	loader *singleflight.Group
+
+	// watches keeps the keys in hotCache watched on their owner
+	watches *watchManager
+
+	stats groupStats
 }
 
 var (
@@ -45,22 +110,258 @@ var (
 	groups = make(map[string]*Group)
 )
 
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+// GroupConfig describes a Group, it is filled in by the GroupOptions
+// given to NewGroupWithOptions
+type GroupConfig struct {
+	// CacheBytes limits the bytes held by both caches together,
+	// 0 disables caching
+	CacheBytes int64
+	// Strategy is the eviction strategy of both caches,
+	// config.Config.CacheStrategy by default
+	Strategy string
+	// MainCacheBytes and HotCacheBytes limit the size of each cache,
+	// CacheBytes if 0
+	MainCacheBytes int64
+	HotCacheBytes  int64
+	// HotCacheRatio is how large hotCache may grow relative to mainCache
+	// before its entries are evicted first, 1/16 by default
+	HotCacheRatio float64
+	// DefaultTTL is how long values loaded by the getter are cached, unless
+	// the getter tells otherwise. 0 means forever.
+	DefaultTTL time.Duration
+	// NegativeTTL is how long a key the getter returns ErrNotFound for is
+	// remembered as missing, 0 asks the getter again on every lookup
+	NegativeTTL time.Duration
+	// RefreshAhead is the part of its TTL after which a value loaded by the
+	// getter is served while being reloaded in the background, so that
+	// popular keys don't wait for the getter once they expire. 0 disables it.
+	RefreshAhead float64
+	// StaleIfError is how long values are kept once expired, to be served
+	// as stale when they can neither be loaded from their owner nor from the
+	// getter. 0 drops values as soon as they expire.
+	StaleIfError time.Duration
+	// SweepInterval is how often expired entries are reclaimed in the
+	// background, spending at most SweepBudget each time. 0 leaves expired
+	// entries until they are looked up or evicted.
+	SweepInterval time.Duration
+	SweepBudget   time.Duration
+	// Peers picks the owner of a key, the group only loads keys locally if nil
+	Peers PeerPicker
+	// AOF logs the values set in and removed from the group, to be replayed
+	// once the node restarts. Writes aren't logged if nil.
+	AOF *AOF
+}
+
+// GroupOption sets a field of GroupConfig
+type GroupOption func(*GroupConfig)
+
+// WithCacheBytes limits the bytes held by both caches of the group together
+func WithCacheBytes(maxBytes int64) GroupOption {
+	return func(c *GroupConfig) {
+		c.CacheBytes = maxBytes
+	}
+}
+
+// WithStrategy sets the eviction strategy of both caches of the group,
+// config.Config.CacheStrategy by default
+func WithStrategy(strategy string) GroupOption {
+	return func(c *GroupConfig) {
+		c.Strategy = strategy
+	}
+}
+
+// WithMainCacheBytes limits the size of mainCache, cacheBytes by default
+func WithMainCacheBytes(maxBytes int64) GroupOption {
+	return func(c *GroupConfig) {
+		c.MainCacheBytes = maxBytes
+	}
+}
+
+// WithHotCacheBytes limits the size of hotCache, cacheBytes by default
+func WithHotCacheBytes(maxBytes int64) GroupOption {
+	return func(c *GroupConfig) {
+		c.HotCacheBytes = maxBytes
+	}
+}
+
+// WithHotCacheRatio sets how large hotCache may grow relative to mainCache
+// before its entries are evicted first
+func WithHotCacheRatio(ratio float64) GroupOption {
+	return func(c *GroupConfig) {
+		c.HotCacheRatio = ratio
+	}
+}
+
+// WithDefaultTTL sets how long values loaded by the getter are cached
+func WithDefaultTTL(ttl time.Duration) GroupOption {
+	return func(c *GroupConfig) {
+		c.DefaultTTL = ttl
+	}
+}
+
+// WithNegativeTTL sets how long keys not found by the getter are
+// remembered as missing
+func WithNegativeTTL(ttl time.Duration) GroupOption {
+	return func(c *GroupConfig) {
+		c.NegativeTTL = ttl
+	}
+}
+
+// WithRefreshAhead reloads values in the background once ratio of their
+// TTL has passed, 0.8 reloads a value with a 10m TTL after 8m
+func WithRefreshAhead(ratio float64) GroupOption {
+	return func(c *GroupConfig) {
+		c.RefreshAhead = ratio
+	}
+}
+
+// WithStaleIfError keeps values for grace once they expire, to serve them
+// as stale when they fail to load again
+func WithStaleIfError(grace time.Duration) GroupOption {
+	return func(c *GroupConfig) {
+		c.StaleIfError = grace
+	}
+}
+
+// WithSweeper reclaims expired entries every interval, spending at most
+// budget each time
+func WithSweeper(interval, budget time.Duration) GroupOption {
+	return func(c *GroupConfig) {
+		c.SweepInterval = interval
+		c.SweepBudget = budget
+	}
+}
+
+// WithPeers makes the group fetch keys from their owner picked by peers,
+// like RegisterPeers
+func WithPeers(peers PeerPicker) GroupOption {
+	return func(c *GroupConfig) {
+		c.Peers = peers
+	}
+}
+
+// WithAOF logs the values set in and removed from the group to aof
+func WithAOF(aof *AOF) GroupOption {
+	return func(c *GroupConfig) {
+		c.AOF = aof
+	}
+}
+
+// validate checks c and fills in the defaults depending on other fields
+func (c *GroupConfig) validate() error {
+	if c.CacheBytes < 0 {
+		return fmt.Errorf("negative cache bytes: %d", c.CacheBytes)
+	}
+	if c.MainCacheBytes < 0 || c.HotCacheBytes < 0 {
+		return fmt.Errorf("negative main or hot cache bytes: %d, %d", c.MainCacheBytes, c.HotCacheBytes)
+	}
+	if c.HotCacheRatio < 0 {
+		return fmt.Errorf("negative hot cache ratio: %v", c.HotCacheRatio)
+	}
+	if c.DefaultTTL < 0 {
+		return fmt.Errorf("negative default ttl: %v", c.DefaultTTL)
+	}
+	if c.NegativeTTL < 0 {
+		return fmt.Errorf("negative negative ttl: %v", c.NegativeTTL)
+	}
+	if c.RefreshAhead < 0 || c.RefreshAhead >= 1 {
+		return fmt.Errorf("refresh ahead ratio out of [0, 1): %v", c.RefreshAhead)
+	}
+	if c.StaleIfError < 0 {
+		return fmt.Errorf("negative stale-if-error grace: %v", c.StaleIfError)
+	}
+	if c.SweepInterval < 0 || c.SweepBudget < 0 {
+		return fmt.Errorf("negative sweep interval or budget: %v, %v", c.SweepInterval, c.SweepBudget)
+	}
+	if c.MainCacheBytes == 0 {
+		c.MainCacheBytes = c.CacheBytes
+	}
+	if c.HotCacheBytes == 0 {
+		c.HotCacheBytes = c.CacheBytes
+	}
+	return nil
+}
+
+// NewGroup creates a group holding at most cacheBytes in its caches, and
+// loading the keys it misses with getter. A group created before with the
+// same name is replaced. It panics if the options are invalid.
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
+	g, err := newGroup(name, getter, true, append([]GroupOption{WithCacheBytes(cacheBytes)}, opts...))
+	if err != nil {
+		panic(err.Error())
+	}
+	return g
+}
+
+// NewGroupWithOptions creates a group loading the keys it misses with
+// getter. Unlike NewGroup, it fails if the name is taken or the options are
+// invalid.
+func NewGroupWithOptions(name string, getter Getter, opts ...GroupOption) (*Group, error) {
+	return newGroup(name, getter, false, opts)
+}
+
+func newGroup(name string, getter Getter, replace bool, opts []GroupOption) (*Group, error) {
+	if name == "" {
+		return nil, fmt.Errorf("group name is required")
+	}
+	if getter == nil {
+		return nil, fmt.Errorf("group %s: nil Getter", name)
+	}
+	c := GroupConfig{
+		Strategy:      config.Config.CacheStrategy,
+		HotCacheRatio: 1.0 / 16,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("group %s: %w", name, err)
+	}
+	mainCache, err := cache.New(c.Strategy, c.MainCacheBytes)
+	if err != nil {
+		return nil, fmt.Errorf("group %s: %w", name, err)
+	}
+
 	mu.Lock()
 	defer mu.Unlock()
+	old, ok := groups[name]
+	if ok && !replace {
+		return nil, fmt.Errorf("group %s already exists", name)
+	}
 	g := &Group{
-		name:       name,
-		getter:     getter,
-		cacheBytes: cacheBytes,
-		mainCache:  cache.NewDefaultCache(false),
-		hotCache:   cache.NewDefaultCache(true),
-		loader:     &singleflight.Group{},
+		name:          name,
+		getter:        getter,
+		cacheBytes:    c.CacheBytes,
+		hotCacheRatio: c.HotCacheRatio,
+		defaultTTL:    c.DefaultTTL,
+		negativeTTL:   c.NegativeTTL,
+		refreshAhead:  c.RefreshAhead,
+		staleIfError:  c.StaleIfError,
+		mainCache:     mainCache,
+		hotKeys:       hotkey.NewDetector(config.Config.HotKeyQPS),
+		peers:         c.Peers,
+		aof:           c.AOF,
+		loader:        &singleflight.Group{},
+		watches:       newWatchManager(),
+	}
+	// a hot copy is watched for as long as it is kept
+	g.hotCache, _ = cache.New(c.Strategy, c.HotCacheBytes, cache.WithEvictedFunc(func(key string, _ cache.Value) {
+		g.watches.evicted(key)
+	}))
+	stopMain := cache.StartSweeper(g.mainCache, c.SweepInterval, c.SweepBudget)
+	stopHot := cache.StartSweeper(g.hotCache, c.SweepInterval, c.SweepBudget)
+	g.stopSweepers = func() {
+		stopMain()
+		stopHot()
+	}
+	if ok {
+		old.stopSweepers()
 	}
 	groups[name] = g
-	return g
+	return g, nil
 }
 
 func GetGroup(name string) *Group {
@@ -70,24 +371,166 @@ func GetGroup(name string) *Group {
 	return g
 }
 
+// allGroups returns the groups created so far, by name
+func allGroups() map[string]*Group {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make(map[string]*Group, len(groups))
+	for name, g := range groups {
+		all[name] = g
+	}
+	return all
+}
+
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext is like Get, but gives up loading key once ctx is done
+func (g *Group) GetContext(ctx context.Context, key string) (value ByteView, err error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
+	ctx, span := tracer().Start(ctx, "Group.Get", trace.WithAttributes(
+		attribute.String("kache.group", g.name),
+		attribute.String("kache.key", key),
+	))
+	defer func() { endSpan(span, err) }()
+	g.stats.gets.Add(1)
 	v, cacheHit := g.lookupCache(key)
+	span.SetAttributes(attribute.Bool("kache.cache_hit", cacheHit))
+	var stale *ByteView
 	if cacheHit {
-		return v, nil
+		if v.notFound {
+			g.stats.cacheHits.Add(1)
+			return ByteView{}, fmt.Errorf("%s/%s: %w", g.name, key, ErrNotFound)
+		}
+		now := time.Now()
+		if v.e.IsZero() || now.Before(v.e) {
+			if !v.refresh.IsZero() && now.After(v.refresh) {
+				g.refresh(key)
+			}
+			g.stats.cacheHits.Add(1)
+			return v, nil
+		}
+		// kept past its expiry for the grace of stale-if-error
+		stale = &v
+	}
+	if g.peers != nil {
+		// keys missed are counted before concurrent loads of them are merged
+		g.hotKeys.Touch(key)
 	}
 
-	return g.load(key)
+	g.stats.loads.Add(1)
+	value, err = g.load(ctx, key)
+	// a caller giving up isn't a failure of the load paths
+	if err != nil && stale != nil && !errors.Is(err, ErrNotFound) && ctx.Err() == nil {
+		g.stats.staleHits.Add(1)
+		span.SetAttributes(attribute.Bool("kache.stale", true))
+		logger.Warn("serving a stale value", logger.F("group", g.name), logger.F("key", key), logger.Err(err))
+		stale.stale = true
+		return *stale, nil
+	}
+	return value, err
 }
 
+// Set stores value for ttl on the node key is allocated to,
+// 0 ttl means never expire
 func (g *Group) Set(key string, value []byte, ttl time.Duration) bool {
+	return g.SetContext(context.Background(), key, value, ttl) == nil
+}
+
+// SetContext is like Set, but reports why the value can't be stored
+func (g *Group) SetContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	if key == "" {
-		return false
+		return fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			if err := peer.Set(ctx, g.name, key, value, ttl); err != nil {
+				return err
+			}
+			// the owner refreshes the other copies, drop ours in the meantime
+			g.hotCache.Remove(key)
+			return nil
+		}
+	}
+	g.setLocally(key, value, ttl)
+	return nil
+}
+
+// setLocally stores key in mainCache regardless of its owner, and drops
+// the copies held by other nodes
+func (g *Group) setLocally(key string, value []byte, ttl time.Duration) {
+	v := ByteView{bts: cloneBytes(value), set: true}
+	if ttl > 0 {
+		v.e = time.Now().Add(ttl)
+		ttl += g.staleIfError
+	}
+	if g.aof == nil {
+		g.mainCache.Set(key, v, ttl)
+	} else {
+		var evict time.Time
+		if ttl > 0 {
+			evict = time.Now().Add(ttl)
+		}
+		err := g.aof.write(setRecord(g.name, key, v, evict), func() {
+			g.mainCache.Set(key, v, ttl)
+		})
+		if err != nil {
+			logger.Error("logging set", logger.F("group", g.name), logger.F("key", key), logger.Err(err))
+		}
+	}
+	if g.peers != nil {
+		if err := g.peers.Invalidate(g.name, key); err != nil {
+			logger.Error("invalidating on peers", logger.F("group", g.name), logger.F("key", key), logger.Err(err))
+		}
+	}
+}
+
+// Remove deletes key from the node it is allocated to, and from the
+// hotCache of every node
+func (g *Group) Remove(key string) bool {
+	return g.RemoveContext(context.Background(), key) == nil
+}
+
+// RemoveContext is like Remove, but reports why key can't be removed
+func (g *Group) RemoveContext(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			if err := peer.Delete(ctx, g.name, key); err != nil {
+				return err
+			}
+			g.hotCache.Remove(key)
+			return nil
+		}
+	}
+	g.removeLocally(key)
+	return nil
+}
+
+// removeLocally deletes key from both caches of this node regardless of its
+// owner, and drops the copies held by other nodes
+func (g *Group) removeLocally(key string) {
+	if g.aof == nil {
+		g.mainCache.Remove(key)
+	} else {
+		err := g.aof.write(removeRecord(g.name, key), func() {
+			g.mainCache.Remove(key)
+		})
+		if err != nil {
+			logger.Error("logging remove", logger.F("group", g.name), logger.F("key", key), logger.Err(err))
+		}
+	}
+	g.hotCache.Remove(key)
+	if g.peers != nil {
+		if err := g.peers.Invalidate(g.name, key); err != nil {
+			logger.Error("invalidating on peers", logger.F("group", g.name), logger.F("key", key), logger.Err(err))
+		}
 	}
-	g.mainCache.Set(key, ByteView{bts: cloneBytes(value)}, ttl)
-	return true
 }
 
 func (g *Group) lookupCache(key string) (value ByteView, ok bool) {
@@ -113,17 +556,33 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 
 // key is not in the local cache, we may have to ask other peers for help,
 // or call local Getter method
-func (g *Group) load(key string) (value ByteView, err error) {
-	viewi, err := g.loader.Do(key, func() (any, error) {
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
+	ctx, span := tracer().Start(ctx, "Group.load")
+	defer func() { endSpan(span, err) }()
+	// the loader runs with a ctx of its own, which is cancelled
+	// once every caller waiting for key has given up
+	viewi, err := g.loader.DoContext(ctx, key, func(ctx context.Context) (any, error) {
+		g.stats.loadsDeduped.Add(1)
 		if g.peers != nil {
 			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err = g.getFromPeer(peer, key); err == nil {
+				value, err := g.getFromPeer(ctx, peer, key)
+				if err == nil {
+					g.stats.peerLoads.Add(1)
 					return value, nil
 				}
-				log.Println("[kache] Failed to get from peer", err)
+				if errors.Is(err, ErrNotFound) {
+					g.stats.peerLoads.Add(1)
+				} else {
+					g.stats.peerErrors.Add(1)
+				}
+				// the owner knows better than the local getter
+				if ctx.Err() != nil || errors.Is(err, ErrNotFound) {
+					return nil, err
+				}
+				logger.Warn("getting from peer, loading locally", logger.F("group", g.name), logger.F("key", key), logger.Err(err))
 			}
 		}
-		return g.getLocally(key)
+		return g.getLocally(ctx, key)
 	})
 	if err == nil {
 		return viewi.(ByteView), nil
@@ -131,35 +590,123 @@ func (g *Group) load(key string) (value ByteView, err error) {
 	return
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
-	v, err := peer.Get(g.name, key)
+// refresh reloads key with the getter in the background, unless it is
+// being reloaded already
+func (g *Group) refresh(key string) {
+	if _, loaded := g.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer g.refreshing.Delete(key)
+		// merged with the loads of callers that missed key meanwhile
+		_, err := g.loader.DoContext(context.Background(), key, func(ctx context.Context) (any, error) {
+			return g.getLocally(ctx, key)
+		})
+		if err != nil {
+			logger.Warn("refreshing, serving the value until it expires", logger.F("group", g.name), logger.F("key", key), logger.Err(err))
+		}
+	}()
+}
+
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (value ByteView, err error) {
+	ctx, span := tracer().Start(ctx, "Group.getFromPeer")
+	defer func() { endSpan(span, err) }()
+	// only hot keys are kept, and watched before fetching them so that a
+	// change made in between is not missed and the stale value is not kept
+	var w *hotWatch
+	if g.cacheBytes > 0 && g.hotKeys.Hot(key) {
+		w = g.watches.watch(ctx, peer, g.name, key, func() {
+			g.hotCache.Remove(key)
+		})
+	}
+	span.SetAttributes(attribute.Bool("kache.hot_copy", w != nil))
+	value, err = peer.Get(ctx, g.name, key)
 	if err != nil {
+		if w != nil {
+			g.watches.done(key, w, false)
+		}
 		return ByteView{}, err
 	}
 
-	// set hotCache
-	go peer.Watch(g.name, key, func(bts []byte) {
-		g.populateCache(key, ByteView{bts: bts}, &g.hotCache)
-	})
-
-	return ByteView{bts: v}, nil
+	if w != nil {
+		// the copy expires along with the value of the owner
+		kept := g.populateCache(key, value, &g.hotCache)
+		if kept && w.fired.Load() {
+			// the key may have changed before the copy was stored
+			g.hotCache.Remove(key)
+		}
+		// the copy may also have been evicted to fit
+		g.watches.done(key, w, kept && g.hotCache.Has(key))
+	}
+	return value, nil
 }
 
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bts, err := g.getter.Get(key)
+func (g *Group) getLocally(ctx context.Context, key string) (value ByteView, err error) {
+	ctx, span := tracer().Start(ctx, "Group.getLocally")
+	defer func() { endSpan(span, err) }()
+	var (
+		bts []byte
+		ttl time.Duration
+	)
+	switch getter := g.getter.(type) {
+	case GetterWithTTL:
+		bts, ttl, err = getter.GetWithTTL(ctx, key)
+	case GetterWithContext:
+		bts, err = getter.GetContext(ctx, key)
+	default:
+		bts, err = g.getter.Get(key)
+	}
+	if err == nil || errors.Is(err, ErrNotFound) {
+		g.stats.localLoads.Add(1)
+	} else {
+		g.stats.localLoadErrs.Add(1)
+	}
+	if errors.Is(err, ErrNotFound) && g.negativeTTL > 0 {
+		// remember the miss, so that the getter isn't flooded with it
+		g.populateCache(key, ByteView{e: time.Now().Add(g.negativeTTL), notFound: true}, &g.mainCache)
+	}
 	if err != nil {
 		return ByteView{}, err
 	}
-	value := ByteView{bts: cloneBytes(bts)}
+	if ttl <= 0 {
+		ttl = g.defaultTTL
+	}
+	value = ByteView{bts: cloneBytes(bts)}
+	if ttl > 0 {
+		now := time.Now()
+		value.e = now.Add(ttl)
+		if g.refreshAhead > 0 {
+			value.refresh = now.Add(time.Duration(float64(ttl) * g.refreshAhead))
+		}
+	}
 	g.populateCache(key, value, &g.mainCache)
 	return value, nil
 }
 
-func (g *Group) populateCache(key string, value ByteView, cache *cache.Cache) {
-	if g.cacheBytes <= 0 {
-		return
+// populateCache stores value in cache until it expires, or until the grace
+// of stale-if-error is over, and evicts entries until both caches fit in
+// cacheBytes. It reports whether value is stored.
+func (g *Group) populateCache(key string, value ByteView, cache *cache.Cache) bool {
+	if g.cacheBytes <= 0 || value.stale {
+		return false
+	}
+	var ttl time.Duration
+	if !value.e.IsZero() {
+		if ttl = time.Until(value.e); ttl <= 0 {
+			return false
+		}
+		if !value.notFound {
+			ttl += g.staleIfError
+		}
 	}
-	(*cache).Set(key, value, time.Duration(0))
+	(*cache).Set(key, value, ttl)
+	g.fit()
+	return true
+}
+
+// fit evicts entries until both caches fit in cacheBytes, hotCache first
+// once it is larger than its share
+func (g *Group) fit() {
 	for {
 		mainBytes := g.mainCache.Bytes()
 		hotBytes := g.hotCache.Bytes()
@@ -167,7 +714,7 @@ func (g *Group) populateCache(key string, value ByteView, cache *cache.Cache) {
 			return
 		}
 		victim := g.mainCache
-		if hotBytes > mainBytes/16 {
+		if float64(hotBytes) > float64(mainBytes)*g.hotCacheRatio {
 			victim = g.hotCache
 		}
 		victim.Shrink()
@@ -1,10 +1,15 @@
 package kache
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/falldio/Kache/pkg/cache"
+	"github.com/falldio/Kache/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -28,8 +33,8 @@ func (m *MockPeer) PickPeer(key string) (PeerGetter, bool) {
 	return args.Get(0).(PeerGetter), args.Bool(1)
 }
 
-func (m *MockPeer) Update(group, key string, value []byte) error {
-	args := m.Called(group, key, value)
+func (m *MockPeer) Invalidate(group, key string) error {
+	args := m.Called(group, key)
 	return args.Error(0)
 }
 
@@ -37,14 +42,27 @@ type MockPeerGetter struct {
 	mock.Mock
 }
 
-func (m *MockPeerGetter) Get(group, key string) ([]byte, error) {
+func (m *MockPeerGetter) Get(ctx context.Context, group, key string) (ByteView, error) {
+	args := m.Called(group, key)
+	if v, ok := args.Get(0).(ByteView); ok {
+		return v, args.Error(1)
+	}
+	return ByteView{bts: args.Get(0).([]byte)}, args.Error(1)
+}
+
+func (m *MockPeerGetter) Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	args := m.Called(group, key, value, ttl)
+	return args.Error(0)
+}
+
+func (m *MockPeerGetter) Delete(ctx context.Context, group, key string) error {
 	args := m.Called(group, key)
-	return args.Get(0).([]byte), args.Error(1)
+	return args.Error(0)
 }
 
-func (m *MockPeerGetter) Watch(group, key string, fn func([]byte)) {
-	fn([]byte(key))
-	m.Called(group, key, fn)
+func (m *MockPeerGetter) Watch(ctx context.Context, group, key string, fn func()) (func(), error) {
+	args := m.Called(group, key, fn)
+	return args.Get(0).(func()), args.Error(1)
 }
 
 func TestNewGroup(t *testing.T) {
@@ -56,6 +74,91 @@ func TestNewGroup(t *testing.T) {
 	assert.Equal(t, groups["scores"], g)
 }
 
+// dropGroups forgets the groups named once the test is over, so that they
+// can be created again by NewGroupWithOptions
+func dropGroups(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, name := range names {
+			if g, ok := groups[name]; ok {
+				g.stopSweepers()
+				delete(groups, name)
+			}
+		}
+	})
+}
+
+func TestNewGroupWithOptions(t *testing.T) {
+	dropGroups(t, "options")
+	peers := &MockPeer{}
+	g, err := NewGroupWithOptions("options", mockGetter, WithCacheBytes(2<<10), WithPeers(peers))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2<<10), g.cacheBytes)
+	assert.Equal(t, 1.0/16, g.hotCacheRatio)
+	assert.Equal(t, peers, g.peers)
+	assert.Equal(t, g, GetGroup("options"))
+
+	// the name is taken
+	_, err = NewGroupWithOptions("options", mockGetter)
+	assert.NotNil(t, err)
+	assert.Equal(t, g, GetGroup("options"))
+
+	for _, invalid := range [][]GroupOption{
+		{WithCacheBytes(-1)},
+		{WithHotCacheBytes(-1)},
+		{WithHotCacheRatio(-0.5)},
+		{WithStrategy("unknown")},
+	} {
+		_, err := NewGroupWithOptions("invalid-options", mockGetter, invalid...)
+		assert.NotNil(t, err)
+	}
+	_, err = NewGroupWithOptions("", mockGetter)
+	assert.NotNil(t, err)
+	_, err = NewGroupWithOptions("invalid-options", nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, GetGroup("invalid-options"))
+}
+
+func TestHotCacheRatio(t *testing.T) {
+	dropGroups(t, "hot-ratio")
+	// with an even ratio, mainCache is evicted first until hotCache is larger
+	g, err := NewGroupWithOptions("hot-ratio", mockGetter, WithCacheBytes(24), WithHotCacheRatio(1))
+	assert.Nil(t, err)
+	for i := 0; i < 8; i++ {
+		g.mainCache.Set(fmt.Sprintf("%d", i), ByteView{bts: []byte("0")}, 0)
+	}
+	for i := 0; i < 2; i++ {
+		g.hotCache.Set(fmt.Sprintf("%d", i), ByteView{bts: []byte("0")}, 0)
+	}
+	g.populateCache("Tom", ByteView{bts: []byte("630")}, &g.hotCache)
+	assert.Equal(t, 7, g.mainCache.Len())
+	assert.Equal(t, 3, g.hotCache.Len())
+}
+
+func TestNewGroupOptions(t *testing.T) {
+	assert.Panics(t, func() { NewGroup("feeds", 2<<10, mockGetter, WithStrategy("unknown")) })
+
+	sessions := NewGroup("sessions", 2<<10, mockGetter, WithStrategy(cache.CACHE_STRATEGY_LFU))
+	feeds := NewGroup("feeds", 2<<10, mockGetter,
+		WithStrategy(cache.CACHE_STRATEGY_FIFO), WithMainCacheBytes(16), WithHotCacheBytes(8))
+	assert.IsType(t, &cache.LFUCache{}, sessions.mainCache)
+	assert.IsType(t, &cache.LFUCache{}, sessions.hotCache)
+	assert.IsType(t, &cache.FIFOCache{}, feeds.mainCache)
+	assert.IsType(t, &cache.FIFOCache{}, feeds.hotCache)
+
+	// each cache keeps to its own budget
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("key%d", i)
+		feeds.populateCache(key, ByteView{bts: []byte("0")}, &feeds.mainCache)
+		feeds.populateCache(key, ByteView{bts: []byte("0")}, &feeds.hotCache)
+		sessions.populateCache(key, ByteView{bts: []byte("0")}, &sessions.mainCache)
+	}
+	assert.LessOrEqual(t, feeds.mainCache.Bytes(), int64(16))
+	assert.LessOrEqual(t, feeds.hotCache.Bytes(), int64(8))
+	assert.Equal(t, 4, sessions.mainCache.Len())
+}
+
 func TestGetter(t *testing.T) {
 	expect := []byte("key")
 	if v, _ := mockGetter.Get("key"); !reflect.DeepEqual(v, expect) {
@@ -95,6 +198,32 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetContext(t *testing.T) {
+	cancelled := make(chan struct{})
+	g := NewGroup("scores", 2<<10, GetterWithContextFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := g.GetContext(ctx, "Tom")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("getter is not cancelled")
+	}
+
+	// plain Getters still work
+	g = NewGroup("scores", 2<<10, mockGetter)
+	v, err := g.GetContext(context.Background(), "Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "Tom", v.String())
+}
+
 func TestGetGroup(t *testing.T) {
 	g := NewGroup("scores", 2<<10, mockGetter)
 	g1 := GetGroup("scores")
@@ -110,6 +239,65 @@ func TestSet(t *testing.T) {
 	assert.Equal(t, false, g.Set("", []byte("630"), 0))
 }
 
+func TestSetContext(t *testing.T) {
+	mockPeer := &MockPeer{}
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeer.On("PickPeer", "Tom").Return(mockPeerGetter, true)
+	mockPeer.On("PickPeer", "Jack").Return(mockPeerGetter, false)
+	mockPeer.On("Invalidate", "scores", "Jack").Return(nil)
+	mockPeerGetter.On("Set", "scores", "Tom", []byte("630"), time.Minute).Return(nil)
+	g := NewGroup("scores", 2<<10, mockGetter)
+	g.RegisterPeers(mockPeer)
+
+	// keys of other nodes are set on their owner
+	g.hotCache.Set("Tom", ByteView{bts: []byte("0")}, 0)
+	assert.Nil(t, g.SetContext(context.Background(), "Tom", []byte("630"), time.Minute))
+	mockPeerGetter.AssertCalled(t, "Set", "scores", "Tom", []byte("630"), time.Minute)
+	assert.False(t, g.hotCache.Has("Tom"))
+	assert.False(t, g.mainCache.Has("Tom"))
+
+	// keys of this node are set locally, and other copies are invalidated
+	assert.Nil(t, g.SetContext(context.Background(), "Jack", []byte("589"), 0))
+	mockPeer.AssertCalled(t, "Invalidate", "scores", "Jack")
+	assert.True(t, g.mainCache.Has("Jack"))
+
+	assert.NotNil(t, g.SetContext(context.Background(), "", []byte("589"), 0))
+}
+
+func TestRemoveContext(t *testing.T) {
+	mockPeer := &MockPeer{}
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeer.On("PickPeer", "Tom").Return(mockPeerGetter, true)
+	mockPeer.On("PickPeer", "Jack").Return(mockPeerGetter, false)
+	mockPeer.On("Invalidate", "scores", "Jack").Return(nil)
+	mockPeerGetter.On("Delete", "scores", "Tom").Return(fmt.Errorf("unavailable")).Once()
+	mockPeerGetter.On("Delete", "scores", "Tom").Return(nil)
+	g := NewGroup("scores", 2<<10, mockGetter)
+	g.RegisterPeers(mockPeer)
+
+	g.hotCache.Set("Tom", ByteView{bts: []byte("630")}, 0)
+	assert.NotNil(t, g.RemoveContext(context.Background(), "Tom"))
+	assert.True(t, g.hotCache.Has("Tom"))
+	assert.Nil(t, g.RemoveContext(context.Background(), "Tom"))
+	assert.False(t, g.hotCache.Has("Tom"))
+
+	g.mainCache.Set("Jack", ByteView{bts: []byte("589")}, 0)
+	assert.Nil(t, g.RemoveContext(context.Background(), "Jack"))
+	mockPeer.AssertCalled(t, "Invalidate", "scores", "Jack")
+	assert.False(t, g.mainCache.Has("Jack"))
+}
+
+func TestRemove(t *testing.T) {
+	g := NewGroup("scores", 2<<10, mockGetter)
+	g.Set("Tom", []byte("630"), 0)
+	g.hotCache.Set("Jack", ByteView{bts: []byte("589")}, 0)
+	assert.True(t, g.Remove("Tom"))
+	assert.True(t, g.Remove("Jack"))
+	assert.False(t, g.mainCache.Has("Tom"))
+	assert.False(t, g.hotCache.Has("Jack"))
+	assert.False(t, g.Remove(""))
+}
+
 func TestLookupCache(t *testing.T) {
 	// cacheBytes <= 0
 	g := NewGroup("scores", 0, mockGetter)
@@ -138,27 +326,324 @@ func TestLoad(t *testing.T) {
 	mockPeer := &MockPeer{}
 	mockPeerGetter := &MockPeerGetter{}
 	mockPeer.On("PickPeer", "Tom").Return(mockPeerGetter, true)
-	mockPeerGetter.On("Watch", "scores", "Tom", mock.AnythingOfType("func([]uint8)")).Return()
+	mockPeerGetter.On("Watch", "scores", "Tom", mock.AnythingOfType("func()")).Return(func() {}, nil)
 	mockCall := mockPeerGetter.On("Get", "scores", "Tom").Return([]byte("630"), nil)
 	g := NewGroup("scores", 2<<10, mockGetter)
 	g.RegisterPeers(mockPeer)
-	g.load("Tom")
+	g.load(context.Background(), "Tom")
 	mockPeer.AssertCalled(t, "PickPeer", "Tom")
 	mockPeerGetter.AssertCalled(t, "Get", "scores", "Tom")
 
 	mockCall.Unset()
 	mockPeerGetter.On("Get", "scores", "Tom").Return([]byte{}, fmt.Errorf("not found"))
-	g.load("Tom")
+	g.load(context.Background(), "Tom")
 	mockPeerGetter.AssertCalled(t, "Get", "scores", "Tom")
 }
 
+// setHotKeyQPS sets the threshold of hot keys for the groups created
+// during the test
+func setHotKeyQPS(t *testing.T, qps float64) {
+	old := config.Config.HotKeyQPS
+	config.Config.HotKeyQPS = qps
+	t.Cleanup(func() { config.Config.HotKeyQPS = old })
+}
+
 func TestGetFromPeer(t *testing.T) {
+	setHotKeyQPS(t, 0)
+	var onInvalidated func()
 	mockPeerGetter := &MockPeerGetter{}
 	mockPeerGetter.On("Get", "scores", "Tom").Return([]byte("630"), nil)
-	mockPeerGetter.On("Watch", "scores", "Tom", mock.AnythingOfType("func([]uint8)")).Return()
+	mockPeerGetter.On("Get", "scores", "Jack").Return([]byte("589"), nil)
+	mockPeerGetter.On("Watch", "scores", "Tom", mock.AnythingOfType("func()")).
+		Run(func(args mock.Arguments) { onInvalidated = args.Get(2).(func()) }).
+		Return(func() {}, nil)
+	mockPeerGetter.On("Watch", "scores", "Jack", mock.AnythingOfType("func()")).
+		Return(func() {}, fmt.Errorf("unavailable"))
 	g := NewGroup("scores", 2<<10, mockGetter)
-	g.getFromPeer(mockPeerGetter, "Tom")
+
+	// a watched key is kept as a hot copy until it changes on the peer
+	v, err := g.getFromPeer(context.Background(), mockPeerGetter, "Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "630", v.String())
 	mockPeerGetter.AssertCalled(t, "Get", "scores", "Tom")
+	assert.True(t, g.hotCache.Has("Tom"))
+	onInvalidated()
+	assert.False(t, g.hotCache.Has("Tom"))
+
+	// a key that can't be watched is not kept
+	v, err = g.getFromPeer(context.Background(), mockPeerGetter, "Jack")
+	assert.Nil(t, err)
+	assert.Equal(t, "589", v.String())
+	assert.False(t, g.hotCache.Has("Jack"))
+}
+
+func TestHotKeyPromotion(t *testing.T) {
+	setHotKeyQPS(t, 1)
+	mockPeer := &MockPeer{}
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeer.On("PickPeer", "Tom").Return(mockPeerGetter, true)
+	mockPeerGetter.On("Get", "scores", "Tom").Return([]byte("630"), nil)
+	mockPeerGetter.On("Watch", "scores", "Tom", mock.AnythingOfType("func()")).Return(func() {}, nil)
+	g := NewGroup("scores", 2<<10, mockGetter)
+	g.RegisterPeers(mockPeer)
+
+	// a cold key is fetched every time
+	_, err := g.GetContext(context.Background(), "Tom")
+	assert.Nil(t, err)
+	assert.False(t, g.hotCache.Has("Tom"))
+	mockPeerGetter.AssertNotCalled(t, "Watch", "scores", "Tom", mock.Anything)
+
+	// once it turns hot, it is kept
+	for i := 0; i < 3; i++ {
+		v, err := g.GetContext(context.Background(), "Tom")
+		assert.Nil(t, err)
+		assert.Equal(t, "630", v.String())
+	}
+	assert.True(t, g.hotCache.Has("Tom"))
+	mockPeerGetter.AssertNumberOfCalls(t, "Get", 2)
+}
+
+func TestDefaultTTL(t *testing.T) {
+	dropGroups(t, "ttl-default")
+	g, err := NewGroupWithOptions("ttl-default", mockGetter, WithCacheBytes(2<<10), WithDefaultTTL(20*time.Millisecond))
+	assert.Nil(t, err)
+	v, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(20*time.Millisecond), v.Expire(), 10*time.Millisecond)
+	assert.True(t, g.mainCache.Has("Tom"))
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, g.mainCache.Has("Tom"))
+
+	_, err = NewGroupWithOptions("ttl-invalid", mockGetter, WithDefaultTTL(-time.Second))
+	assert.NotNil(t, err)
+}
+
+func TestNegativeTTL(t *testing.T) {
+	dropGroups(t, "negative")
+	loads := 0
+	g, err := NewGroupWithOptions("negative", GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}), WithCacheBytes(2<<10), WithNegativeTTL(20*time.Millisecond))
+	assert.Nil(t, err)
+
+	// misses are remembered until the tombstone expires
+	for i := 0; i < 3; i++ {
+		_, err := g.Get("Tom")
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, 1, loads)
+	time.Sleep(30 * time.Millisecond)
+	_, err = g.Get("Tom")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 2, loads)
+
+	// setting the key replaces the tombstone
+	g.Set("Tom", []byte("630"), 0)
+	v, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "630", v.String())
+
+	// without a negative TTL, misses are not remembered
+	g = NewGroup("negative", 2<<10, g.getter)
+	_, err = g.Get("Jack")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = g.Get("Jack")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 4, loads)
+}
+
+func TestLoadNotFoundFromPeer(t *testing.T) {
+	mockPeer := &MockPeer{}
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeer.On("PickPeer", "Tom").Return(mockPeerGetter, true)
+	mockPeerGetter.On("Get", "scores", "Tom").Return([]byte{}, fmt.Errorf("peer: %w", ErrNotFound))
+	loads := 0
+	g := NewGroup("scores", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}))
+	g.RegisterPeers(mockPeer)
+
+	// the owner's answer is final, the local getter is not asked
+	_, err := g.Get("Tom")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 0, loads)
+}
+
+func TestRefreshAhead(t *testing.T) {
+	dropGroups(t, "refresh")
+	var loads atomic.Int32
+	release := make(chan struct{})
+	g, err := NewGroupWithOptions("refresh", GetterWithTTLFunc(func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+		if n := loads.Add(1); n > 1 {
+			<-release
+			return []byte(fmt.Sprintf("v%d", n)), 100 * time.Millisecond, nil
+		}
+		return []byte("v1"), 100 * time.Millisecond, nil
+	}), WithCacheBytes(2<<10), WithRefreshAhead(0.5))
+	assert.Nil(t, err)
+
+	v, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v.String())
+	time.Sleep(60 * time.Millisecond)
+
+	// past the threshold, the cached value is served while it is reloaded once
+	for i := 0; i < 3; i++ {
+		v, err = g.Get("Tom")
+		assert.Nil(t, err)
+		assert.Equal(t, "v1", v.String())
+	}
+	close(release)
+	assert.Eventually(t, func() bool {
+		v, err := g.Get("Tom")
+		return err == nil && v.String() == "v2"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), loads.Load())
+
+	_, err = NewGroupWithOptions("refresh-invalid", mockGetter, WithRefreshAhead(1))
+	assert.NotNil(t, err)
+}
+
+func TestRefreshAheadFailure(t *testing.T) {
+	dropGroups(t, "refresh-failure")
+	var loads atomic.Int32
+	g, err := NewGroupWithOptions("refresh-failure", GetterWithTTLFunc(func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+		if loads.Add(1) > 1 {
+			return nil, 0, fmt.Errorf("unavailable")
+		}
+		return []byte("v1"), 50 * time.Millisecond, nil
+	}), WithCacheBytes(2<<10), WithRefreshAhead(0.2))
+	assert.Nil(t, err)
+
+	_, err = g.Get("Tom")
+	assert.Nil(t, err)
+	time.Sleep(20 * time.Millisecond)
+	// a failed reload keeps the value until its hard TTL
+	v, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v.String())
+	assert.Eventually(t, func() bool { return loads.Load() == 2 }, time.Second, time.Millisecond)
+	v, err = g.Get("Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v.String())
+	time.Sleep(40 * time.Millisecond)
+	_, err = g.Get("Tom")
+	assert.NotNil(t, err)
+}
+
+func TestStaleIfError(t *testing.T) {
+	dropGroups(t, "stale")
+	var failure error
+	g, err := NewGroupWithOptions("stale", GetterFunc(func(key string) ([]byte, error) {
+		if failure != nil {
+			return nil, failure
+		}
+		return []byte("630"), nil
+	}), WithCacheBytes(2<<10), WithDefaultTTL(10*time.Millisecond), WithStaleIfError(50*time.Millisecond))
+	assert.Nil(t, err)
+
+	v, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.False(t, v.Stale())
+
+	// an expired value is served as stale while it fails to load
+	failure = fmt.Errorf("unavailable")
+	time.Sleep(20 * time.Millisecond)
+	v, err = g.Get("Tom")
+	assert.Nil(t, err)
+	assert.True(t, v.Stale())
+	assert.Equal(t, "630", v.String())
+	// nor to a caller that has given up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = g.GetContext(ctx, "Tom")
+	assert.ErrorIs(t, err, context.Canceled)
+	// but not past the grace
+	time.Sleep(50 * time.Millisecond)
+	_, err = g.Get("Tom")
+	assert.NotNil(t, err)
+
+	// a key deleted from the source isn't served
+	failure = nil
+	_, err = g.Get("Jack")
+	assert.Nil(t, err)
+	failure = fmt.Errorf("Jack: %w", ErrNotFound)
+	time.Sleep(20 * time.Millisecond)
+	_, err = g.Get("Jack")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewGroupWithOptions("stale-invalid", mockGetter, WithStaleIfError(-time.Second))
+	assert.NotNil(t, err)
+}
+
+func TestGroupSweeper(t *testing.T) {
+	dropGroups(t, "ttl-sweep")
+	g, err := NewGroupWithOptions("ttl-sweep", mockGetter, WithCacheBytes(2<<10),
+		WithDefaultTTL(time.Millisecond), WithSweeper(time.Millisecond, time.Millisecond))
+	assert.Nil(t, err)
+	_, err = g.Get("Tom")
+	assert.Nil(t, err)
+	// expired entries are reclaimed without being looked up
+	assert.Eventually(t, func() bool { return g.mainCache.Bytes() == 0 }, time.Second, time.Millisecond)
+
+	_, err = NewGroupWithOptions("ttl-sweep-invalid", mockGetter, WithSweeper(-time.Second, 0))
+	assert.NotNil(t, err)
+}
+
+func TestGetterWithTTL(t *testing.T) {
+	dropGroups(t, "ttl-getter")
+	getter := GetterWithTTLFunc(func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+		if key == "Tom" {
+			return []byte("630"), 20 * time.Millisecond, nil
+		}
+		return []byte("589"), 0, nil
+	})
+	v, err := getter.Get("Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "630", string(v))
+
+	g, err := NewGroupWithOptions("ttl-getter", getter, WithCacheBytes(2<<10), WithDefaultTTL(time.Hour))
+	assert.Nil(t, err)
+	// the getter decides, unless it leaves it to the group
+	tom, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(20*time.Millisecond), tom.Expire(), 10*time.Millisecond)
+	jack, err := g.Get("Jack")
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), jack.Expire(), time.Second)
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, g.mainCache.Has("Tom"))
+	assert.True(t, g.mainCache.Has("Jack"))
+}
+
+func TestGetFromPeerExpire(t *testing.T) {
+	setHotKeyQPS(t, 0)
+	cancelled := false
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeerGetter.On("Get", "scores", "Tom").
+		Return(ByteView{bts: []byte("630"), e: time.Now().Add(20 * time.Millisecond)}, nil)
+	mockPeerGetter.On("Get", "scores", "Jack").
+		Return(ByteView{bts: []byte("589"), e: time.Now().Add(-time.Second)}, nil)
+	mockPeerGetter.On("Watch", "scores", mock.Anything, mock.AnythingOfType("func()")).
+		Return(func() { cancelled = true }, nil)
+	g := NewGroup("scores", 2<<10, mockGetter)
+
+	// the hot copy expires along with the value of the owner
+	_, err := g.getFromPeer(context.Background(), mockPeerGetter, "Tom")
+	assert.Nil(t, err)
+	assert.True(t, g.hotCache.Has("Tom"))
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, g.hotCache.Has("Tom"))
+	assert.True(t, cancelled)
+
+	// values already expired are not kept, nor watched
+	cancelled = false
+	_, err = g.getFromPeer(context.Background(), mockPeerGetter, "Jack")
+	assert.Nil(t, err)
+	assert.False(t, g.hotCache.Has("Jack"))
+	assert.True(t, cancelled)
 }
 
 func TestPopulateCache(t *testing.T) {
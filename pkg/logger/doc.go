@@ -0,0 +1,9 @@
+// Package logger is the structured, leveled logger of kache. Every package
+// logs through the Logger set with Set, logrus by default, so that an
+// application can route the logs of the cache to its own logger.
+//
+// Events on the hot path are logged at debug level behind DebugEnabled, so
+// that their fields aren't even built unless debug logging is on. Cached
+// values are wrapped with Value, which hides them unless SetLogValues(true)
+// is called, as they may hold personal data.
+package logger
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// Field is a key-value pair attached to a log event
+type Field struct {
+	Key   string
+	Value any
+}
+
+// F returns a Field, use Value instead for the values of the cache
+func F(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err returns the error field of an event
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Logger is what kache logs to
+type Logger interface {
+	// Enabled reports whether events of level are logged, callers check it
+	// before building costly fields
+	Enabled(level Level) bool
+	Log(level Level, msg string, fields ...Field)
+}
+
+type holder struct {
+	l Logger
+}
+
+var (
+	current   atomic.Pointer[holder]
+	logValues atomic.Bool
+)
+
+func init() {
+	Set(NewLogrus(logrus.StandardLogger()))
+}
+
+// Set makes l the logger of kache, nil discards every event
+func Set(l Logger) {
+	if l == nil {
+		l = Discard
+	}
+	current.Store(&holder{l})
+}
+
+// Get returns the logger of kache
+func Get() Logger {
+	return current.Load().l
+}
+
+// SetLogValues tells whether Value fields are logged as they are, instead
+// of being redacted
+func SetLogValues(on bool) {
+	logValues.Store(on)
+}
+
+// Value returns a field holding a value of the cache, redacted unless
+// SetLogValues(true) has been called
+func Value(key string, v any) Field {
+	return Field{Key: key, Value: redacted{v}}
+}
+
+type redacted struct {
+	v any
+}
+
+func (r redacted) String() string {
+	if !logValues.Load() {
+		return "<redacted>"
+	}
+	return fmt.Sprint(r.v)
+}
+
+// MarshalText redacts the value for formatters encoding fields, like JSON
+func (r redacted) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// DebugEnabled reports whether debug events are logged, so that hot paths
+// can skip them for the cost of a branch
+func DebugEnabled() bool {
+	return Get().Enabled(DebugLevel)
+}
+
+func Debug(msg string, fields ...Field) {
+	log(DebugLevel, msg, fields)
+}
+
+func Info(msg string, fields ...Field) {
+	log(InfoLevel, msg, fields)
+}
+
+func Warn(msg string, fields ...Field) {
+	log(WarnLevel, msg, fields)
+}
+
+func Error(msg string, fields ...Field) {
+	log(ErrorLevel, msg, fields)
+}
+
+func log(level Level, msg string, fields []Field) {
+	if l := Get(); l.Enabled(level) {
+		l.Log(level, msg, fields...)
+	}
+}
+
+// Discard is a Logger dropping every event
+var Discard Logger = discard{}
+
+type discard struct{}
+
+func (discard) Enabled(Level) bool          { return false }
+func (discard) Log(Level, string, ...Field) {}
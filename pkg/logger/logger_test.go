@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type event struct {
+	level  Level
+	msg    string
+	fields []Field
+}
+
+// recorder keeps the events of level and above
+type recorder struct {
+	level  Level
+	events []event
+}
+
+func (r *recorder) Enabled(level Level) bool {
+	return level >= r.level
+}
+
+func (r *recorder) Log(level Level, msg string, fields ...Field) {
+	r.events = append(r.events, event{level, msg, fields})
+}
+
+// use sets l as the logger until the test is over
+func use(t *testing.T, l Logger) {
+	old := Get()
+	Set(l)
+	t.Cleanup(func() { Set(old) })
+}
+
+func TestLevels(t *testing.T) {
+	r := &recorder{level: InfoLevel}
+	use(t, r)
+
+	if DebugEnabled() {
+		t.Fatalf("expect debug disabled at info level")
+	}
+	Debug("dropped")
+	Info("kept", F("key", "Tom"))
+	Warn("kept")
+	Error("kept", Err(errors.New("boom")))
+	if len(r.events) != 3 {
+		t.Fatalf("expect 3 events, got %v", r.events)
+	}
+	if r.events[0].level != InfoLevel || r.events[0].fields[0] != F("key", "Tom") {
+		t.Fatalf("unexpected event %v", r.events[0])
+	}
+
+	r.level = DebugLevel
+	if !DebugEnabled() {
+		t.Fatalf("expect debug enabled at debug level")
+	}
+
+	// nil discards everything
+	Set(nil)
+	if DebugEnabled() || Get().Enabled(ErrorLevel) {
+		t.Fatalf("expect every level disabled")
+	}
+}
+
+func TestValue(t *testing.T) {
+	f := Value("value", "630")
+	if got := fmt.Sprint(f.Value); got != "<redacted>" {
+		t.Fatalf("expect the value redacted by default, got %s", got)
+	}
+	SetLogValues(true)
+	defer SetLogValues(false)
+	if got := fmt.Sprint(f.Value); got != "630" {
+		t.Fatalf("expect 630, got %s", got)
+	}
+}
+
+func TestLogrus(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	use(t, NewLogrus(l))
+
+	Debug("dropped")
+	Info("cache set", F("key", "Tom"), Value("value", "630"))
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Fatalf("expect debug events dropped at info level, got %q", out)
+	}
+	if want := `level=info msg="cache set" key=Tom value="<redacted>"`; !strings.Contains(out, want) {
+		t.Fatalf("expect %q, got %q", want, out)
+	}
+
+	l.SetLevel(logrus.DebugLevel)
+	if !DebugEnabled() {
+		t.Fatalf("expect debug enabled along with logrus")
+	}
+}
+
+func TestLogrusJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(&logrus.JSONFormatter{DisableTimestamp: true})
+	use(t, NewLogrus(l))
+
+	Info("cache set", Value("value", "630"))
+	if want := `"value":"\u003credacted\u003e"`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("expect %q, got %q", want, buf.String())
+	}
+	buf.Reset()
+	SetLogValues(true)
+	defer SetLogValues(false)
+	Info("cache set", Value("value", "630"))
+	if want := `"value":"630"`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("expect %q, got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,43 @@
+package logger
+
+import "github.com/sirupsen/logrus"
+
+// Logrus logs to a logrus.Logger, at the level of the logrus.Logger
+type Logrus struct {
+	l *logrus.Logger
+}
+
+func NewLogrus(l *logrus.Logger) *Logrus {
+	return &Logrus{l: l}
+}
+
+func (l *Logrus) Enabled(level Level) bool {
+	return l.l.IsLevelEnabled(logrusLevel(level))
+}
+
+func (l *Logrus) Log(level Level, msg string, fields ...Field) {
+	if len(fields) == 0 {
+		l.l.Log(logrusLevel(level), msg)
+		return
+	}
+	f := make(logrus.Fields, len(fields))
+	for _, field := range fields {
+		f[field.Key] = field.Value
+	}
+	l.l.WithFields(f).Log(logrusLevel(level), msg)
+}
+
+func logrusLevel(level Level) logrus.Level {
+	switch level {
+	case DebugLevel:
+		return logrus.DebugLevel
+	case InfoLevel:
+		return logrus.InfoLevel
+	case WarnLevel:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
+
+var _ Logger = (*Logrus)(nil)
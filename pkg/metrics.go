@@ -0,0 +1,203 @@
+package kache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/falldio/Kache/pkg/cache"
+	"github.com/falldio/Kache/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultMetricsPath = "/metrics"
+
+var (
+	serverGets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kache",
+		Subsystem: "server",
+		Name:      "get_requests_total",
+		Help:      "Get RPCs served, by group and gRPC status code.",
+	}, []string{"group", "code"})
+	serverGetDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kache",
+		Subsystem: "server",
+		Name:      "get_duration_seconds",
+		Help:      "Time taken to serve Get RPCs, by group.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16), // 100µs to 3.3s
+	}, []string{"group"})
+	peerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kache",
+		Subsystem: "peer",
+		Name:      "errors_total",
+		Help:      "RPCs to peers that failed, by peer address and method.",
+	}, []string{"peer", "method"})
+)
+
+// observeServerGet records a Get RPC for group that took d. Groups that
+// don't exist are not labelled, so that callers can't blow up the series.
+func observeServerGet(group string, err error, d time.Duration) {
+	if GetGroup(group) == nil {
+		group = ""
+	}
+	code := status.Code(err)
+	if errors.Is(err, ErrNotFound) {
+		code = codes.NotFound
+	}
+	serverGets.WithLabelValues(group, code.String()).Inc()
+	serverGetDuration.WithLabelValues(group).Observe(d.Seconds())
+}
+
+// observePeerError counts err returned by method of peer, unless it is an
+// answer of the peer or the caller gave up
+func observePeerError(peer, method string, err error) {
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled {
+		return
+	}
+	peerErrors.WithLabelValues(peer, method).Inc()
+}
+
+var (
+	groupCounterDescs = []struct {
+		desc  *prometheus.Desc
+		value func(Stats) int64
+	}{
+		{groupDesc("gets_total", "Lookups, including those of peers."), func(s Stats) int64 { return s.Gets }},
+		{groupDesc("cache_hits_total", "Lookups answered by either cache."), func(s Stats) int64 { return s.CacheHits }},
+		{groupDesc("stale_hits_total", "Lookups answered by an expired value that failed to load."), func(s Stats) int64 { return s.StaleHits }},
+		{groupDesc("loads_total", "Lookups missing both caches."), func(s Stats) int64 { return s.Loads }},
+		{groupDesc("loads_deduped_total", "Loads left once concurrent ones are merged."), func(s Stats) int64 { return s.LoadsDeduped }},
+		{groupDesc("peer_loads_total", "Loads answered by the owner of the key."), func(s Stats) int64 { return s.PeerLoads }},
+		{groupDesc("peer_errors_total", "Loads the owner of the key failed to answer."), func(s Stats) int64 { return s.PeerErrors }},
+		{groupDesc("local_loads_total", "Loads answered by the getter."), func(s Stats) int64 { return s.LocalLoads }},
+		{groupDesc("local_load_errors_total", "Loads the getter failed to answer."), func(s Stats) int64 { return s.LocalLoadErrs }},
+		{groupDesc("server_requests_total", "Lookups made by peers."), func(s Stats) int64 { return s.ServerRequests }},
+	}
+	cacheLabels          = []string{"group", "cache"}
+	cacheHitsDesc        = prometheus.NewDesc("kache_cache_hits_total", "Lookups of keys present in the cache.", cacheLabels, nil)
+	cacheMissesDesc      = prometheus.NewDesc("kache_cache_misses_total", "Lookups of keys absent from or expired in the cache.", cacheLabels, nil)
+	cacheEvictionsDesc   = prometheus.NewDesc("kache_cache_evictions_total", "Entries removed from the cache to make room.", cacheLabels, nil)
+	cacheExpirationsDesc = prometheus.NewDesc("kache_cache_expirations_total", "Entries removed from the cache as expired.", cacheLabels, nil)
+	cacheBytesDesc       = prometheus.NewDesc("kache_cache_bytes", "Bytes held by the cache.", cacheLabels, nil)
+	cacheItemsDesc       = prometheus.NewDesc("kache_cache_items", "Entries held by the cache.", cacheLabels, nil)
+	peersDesc            = prometheus.NewDesc("kache_peers", "Members of the cluster keys are allocated to, this node included.", nil, nil)
+)
+
+func groupDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc("kache_group_"+name, help, []string{"group"}, nil)
+}
+
+// collector reads the counters of the groups and of the caches when
+// Prometheus scrapes them, instead of keeping a copy up to date
+type collector struct {
+	server *Server // nil if the node has no peers
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range groupCounterDescs {
+		ch <- d.desc
+	}
+	for _, d := range []*prometheus.Desc{cacheHitsDesc, cacheMissesDesc, cacheEvictionsDesc, cacheExpirationsDesc, cacheBytesDesc, cacheItemsDesc} {
+		ch <- d
+	}
+	if c.server != nil {
+		ch <- peersDesc
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	for name, g := range allGroups() {
+		s := g.Stats()
+		for _, d := range groupCounterDescs {
+			ch <- prometheus.MustNewConstMetric(d.desc, prometheus.CounterValue, float64(d.value(s)), name)
+		}
+		for which, cs := range map[string]cache.Stats{"main": s.MainCache, "hot": s.HotCache} {
+			ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(cs.Hits), name, which)
+			ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(cs.Misses), name, which)
+			ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(cs.Evictions), name, which)
+			ch <- prometheus.MustNewConstMetric(cacheExpirationsDesc, prometheus.CounterValue, float64(cs.Expirations), name, which)
+			ch <- prometheus.MustNewConstMetric(cacheBytesDesc, prometheus.GaugeValue, float64(cs.Bytes), name, which)
+			ch <- prometheus.MustNewConstMetric(cacheItemsDesc, prometheus.GaugeValue, float64(cs.Items), name, which)
+		}
+	}
+	if c.server != nil {
+		ch <- prometheus.MustNewConstMetric(peersDesc, prometheus.GaugeValue, float64(c.server.peerCount()))
+	}
+}
+
+// MetricsServer exposes the counters of the local node to Prometheus on
+// /metrics
+type MetricsServer struct {
+	addr    string // address:port
+	mu      sync.Mutex
+	running bool
+	srv     *http.Server
+	handler http.Handler
+}
+
+// NewMetricsServer returns a MetricsServer reporting the local groups, and
+// the members of the cluster of server unless it is nil
+func NewMetricsServer(addr string, server *Server) *MetricsServer {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(serverGets, serverGetDuration, peerErrors, collector{server: server})
+	return &MetricsServer{
+		addr:    addr,
+		handler: promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+	}
+}
+
+func (m *MetricsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != defaultMetricsPath {
+		http.NotFound(w, r)
+		return
+	}
+	m.handler.ServeHTTP(w, r)
+}
+
+// Start listens on the metrics address and serves scrapes until Stop is
+// called.
+func (m *MetricsServer) Start() error {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return fmt.Errorf("metrics server already started")
+	}
+	ln, err := net.Listen("tcp", m.addr)
+	if err != nil {
+		m.mu.Unlock()
+		return fmt.Errorf("starting to listen on %s: %w", m.addr, err)
+	}
+	m.running = true
+	m.srv = &http.Server{Handler: m}
+	srv := m.srv
+	m.mu.Unlock()
+
+	logger.Info("metrics server is running", logger.F("addr", m.addr))
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("starting to serve: %w", err)
+	}
+	return nil
+}
+
+// Stop gracefully shuts the metrics server down.
+func (m *MetricsServer) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.running {
+		return nil
+	}
+	m.running = false
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.srv.Shutdown(ctx)
+}
+
+var _ http.Handler = (*MetricsServer)(nil)
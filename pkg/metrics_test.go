@@ -0,0 +1,60 @@
+package kache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsServer(t *testing.T) {
+	dropGroups(t, "metrics")
+	serverGets.Reset()
+	serverGetDuration.Reset()
+	peerErrors.Reset()
+	_, err := NewGroupWithOptions("metrics", GetterFunc(func(key string) ([]byte, error) {
+		if key == "broken" {
+			return nil, errors.New("db is down")
+		}
+		return []byte(key), nil
+	}), WithCacheBytes(2<<10))
+	assert.Nil(t, err)
+	addr, server := startPeerServer(t)
+	assert.Nil(t, server.SetPeers(addr, "127.0.0.1:1"))
+	c := NewClient(addr)
+	defer c.Close()
+	_, err = c.Get(context.Background(), "metrics", "Tom")
+	assert.Nil(t, err)
+	_, err = c.Get(context.Background(), "metrics", "broken")
+	assert.NotNil(t, err)
+	// nothing listens there
+	assert.NotNil(t, NewClient("127.0.0.1:1").Set(context.Background(), "metrics", "Tom", nil, 0))
+
+	m := NewMetricsServer("localhost:9100", server)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	for _, series := range []string{
+		`kache_server_get_requests_total{code="OK",group="metrics"} 1`,
+		`kache_server_get_requests_total{code="Unknown",group="metrics"} 1`,
+		`kache_server_get_duration_seconds_count{group="metrics"} 2`,
+		`kache_group_gets_total{group="metrics"} 2`,
+		`kache_group_local_loads_total{group="metrics"} 1`,
+		`kache_group_local_load_errors_total{group="metrics"} 1`,
+		`kache_cache_items{cache="main",group="metrics"} 1`,
+		`kache_cache_bytes{cache="main",group="metrics"} 6`,
+		`kache_cache_items{cache="hot",group="metrics"} 0`,
+		`kache_peer_errors_total{method="Set",peer="127.0.0.1:1"} 1`,
+		`kache_peers 2`,
+	} {
+		assert.Contains(t, body, series)
+	}
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,11 +1,25 @@
 package kache
 
+import (
+	"context"
+	"time"
+)
+
 type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
-	Update(group, key string, value []byte) error
+	// Invalidate drops the copies of key held in the hotCache of other nodes,
+	// the peers watching key are told about it shortly afterwards
+	Invalidate(group, key string) error
 }
 
 type PeerGetter interface {
-	Get(group, key string) ([]byte, error)
-	Watch(group, key string, fn func([]byte))
+	// Get returns the value of key held by the peer, which expires along
+	// with the copy of the peer
+	Get(ctx context.Context, group, key string) (ByteView, error)
+	Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, group, key string) error
+	// Watch calls onInvalidated once key changes on the peer, or once the peer
+	// can no longer tell about its changes. ctx bounds setting up the watch,
+	// and cancel stops it.
+	Watch(ctx context.Context, group, key string, onInvalidated func()) (cancel func(), err error)
 }
@@ -23,6 +23,13 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type KacheClient interface {
 	Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Watch streams the keys changed on the peer, so that copies of them
+	// held by the caller can be dropped
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Kache_WatchClient, error)
+	// Stats reports the counters of the groups of the peer
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
 }
 
 type kacheClient struct {
@@ -42,11 +49,77 @@ func (c *kacheClient) Get(ctx context.Context, in *Request, opts ...grpc.CallOpt
 	return out, nil
 }
 
+func (c *kacheClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	out := new(SetResponse)
+	err := c.cc.Invoke(ctx, "/kachepb.Kache/Set", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kacheClient) Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, "/kachepb.Kache/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kacheClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Kache_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Kache_ServiceDesc.Streams[0], "/kachepb.Kache/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kacheWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Kache_WatchClient interface {
+	Recv() (*InvalidationBatch, error)
+	grpc.ClientStream
+}
+
+type kacheWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *kacheWatchClient) Recv() (*InvalidationBatch, error) {
+	m := new(InvalidationBatch)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *kacheClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/kachepb.Kache/Stats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KacheServer is the server API for Kache service.
 // All implementations must embed UnimplementedKacheServer
 // for forward compatibility
 type KacheServer interface {
 	Get(context.Context, *Request) (*Response, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Delete(context.Context, *Request) (*DeleteResponse, error)
+	// Watch streams the keys changed on the peer, so that copies of them
+	// held by the caller can be dropped
+	Watch(*WatchRequest, Kache_WatchServer) error
+	// Stats reports the counters of the groups of the peer
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
 	mustEmbedUnimplementedKacheServer()
 }
 
@@ -57,6 +130,18 @@ type UnimplementedKacheServer struct {
 func (UnimplementedKacheServer) Get(context.Context, *Request) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
 }
+func (UnimplementedKacheServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedKacheServer) Delete(context.Context, *Request) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedKacheServer) Watch(*WatchRequest, Kache_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedKacheServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
 func (UnimplementedKacheServer) mustEmbedUnimplementedKacheServer() {}
 
 // UnsafeKacheServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +173,81 @@ func _Kache_Get_Handler(srv interface{}, ctx context.Context, dec func(interface
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Kache_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KacheServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kachepb.Kache/Set",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KacheServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kache_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KacheServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kachepb.Kache/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KacheServer).Delete(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kache_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KacheServer).Watch(m, &kacheWatchServer{stream})
+}
+
+type Kache_WatchServer interface {
+	Send(*InvalidationBatch) error
+	grpc.ServerStream
+}
+
+type kacheWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *kacheWatchServer) Send(m *InvalidationBatch) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Kache_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KacheServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kachepb.Kache/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KacheServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Kache_ServiceDesc is the grpc.ServiceDesc for Kache service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +259,25 @@ var Kache_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Get",
 			Handler:    _Kache_Get_Handler,
 		},
+		{
+			MethodName: "Set",
+			Handler:    _Kache_Set_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Kache_Delete_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _Kache_Stats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Kache_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/proto/kachepb.proto",
 }
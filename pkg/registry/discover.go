@@ -1,21 +1,85 @@
 package registry
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/falldio/Kache/pkg/logger"
 	clientv3 "go.etcd.io/etcd/client/v3"
-	"go.etcd.io/etcd/client/v3/naming/resolver"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"go.etcd.io/etcd/client/v3/naming/endpoints"
 )
 
-func ETCDDial(c *clientv3.Client, service string) (*grpc.ClientConn, error) {
-	etcdResolver, err := resolver.NewBuilder(c)
+// how long to wait before watching again after an etcd watch fails
+const watchRetryInterval = time.Second
+
+// Watch keeps track of the instances registered under service, and calls
+// onChange with the weights of all of them, keyed by address, whenever an
+// instance joins or its lease expires. It retries on watch failures and
+// returns once stop is closed or receives a value.
+func Watch(service string, stop chan error, onChange func(weights map[string]int)) error {
+	cli, err := clientv3.New(DefaultETCDConfig)
+	if err != nil {
+		return fmt.Errorf("creating etcd client: %w", err)
+	}
+	defer cli.Close()
+
+	em, err := endpoints.NewManager(cli, service)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	for ctx.Err() == nil {
+		wch, err := em.NewWatchChannel(ctx)
+		if err != nil {
+			logger.Warn("watching endpoints", logger.F("service", service), logger.Err(err))
+		} else {
+			// the first batch of a new watch channel carries every live instance,
+			// so start from scratch in case some of them left while not watching
+			members := make(map[string]endpoints.Endpoint)
+			for updates := range wch {
+				for _, up := range updates {
+					switch up.Op {
+					case endpoints.Add:
+						members[up.Key] = up.Endpoint
+					case endpoints.Delete:
+						delete(members, up.Key)
+					}
+				}
+				weights := make(map[string]int, len(members))
+				for _, ep := range members {
+					weights[ep.Addr] = weightOf(ep)
+				}
+				onChange(weights)
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(watchRetryInterval):
+		}
+	}
+	return nil
+}
+
+// weightOf reads the weight from the metadata of ep, which has been through
+// a json round trip. Instances registered without a weight count as 1.
+func weightOf(ep endpoints.Endpoint) int {
+	md, ok := ep.Metadata.(map[string]any)
+	if !ok {
+		return 1
+	}
+	w, ok := md["weight"].(float64)
+	if !ok || w < 1 {
+		return 1
 	}
-	return grpc.Dial(
-		"etcd:///"+service,
-		grpc.WithResolvers(etcdResolver),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-	)
+	return int(w)
 }
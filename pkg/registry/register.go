@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/falldio/Kache/pkg/logger"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/naming/endpoints"
 )
@@ -17,17 +17,22 @@ var (
 	}
 )
 
+// Metadata is stored along with the address of every registered instance
+type Metadata struct {
+	Weight int `json:"weight"` // share of the keys relative to the other instances
+}
+
 // add kv to etcd under lease mode
-func etcdAdd(c *clientv3.Client, lid clientv3.LeaseID, service string, addr string) error {
+func etcdAdd(c *clientv3.Client, lid clientv3.LeaseID, service string, addr string, md Metadata) error {
 	em, err := endpoints.NewManager(c, service)
 	if err != nil {
 		return err
 	}
-	return em.AddEndpoint(c.Ctx(), service+"/"+addr, endpoints.Endpoint{Addr: addr}, clientv3.WithLease(lid))
+	return em.AddEndpoint(c.Ctx(), service+"/"+addr, endpoints.Endpoint{Addr: addr, Metadata: md}, clientv3.WithLease(lid))
 }
 
 // register a service to etcd
-func Register(service string, addr string, stop chan error) error {
+func Register(service string, addr string, md Metadata, stop chan error) error {
 	cli, err := clientv3.New(DefaultETCDConfig)
 	if err != nil {
 		return fmt.Errorf("creating etcd client: %w", err)
@@ -40,7 +45,7 @@ func Register(service string, addr string, stop chan error) error {
 	}
 	leaseId := resp.ID
 
-	err = etcdAdd(cli, leaseId, service, addr)
+	err = etcdAdd(cli, leaseId, service, addr, md)
 	if err != nil {
 		return fmt.Errorf("adding etcd record: %w", err)
 	}
@@ -48,20 +53,23 @@ func Register(service string, addr string, stop chan error) error {
 	if err != nil {
 		return fmt.Errorf("setting keepalive: %w", err)
 	}
-	log.Printf("[%s] servise registered\n", addr)
+	logger.Info("service registered", logger.F("service", service), logger.F("addr", addr))
 	for {
 		select {
 		case err := <-stop:
 			if err != nil {
-				log.Println(err)
+				logger.Error("service stopped", logger.F("addr", addr), logger.Err(err))
+			}
+			if _, rerr := cli.Revoke(context.Background(), leaseId); rerr != nil {
+				logger.Warn("revoking lease", logger.F("addr", addr), logger.Err(rerr))
 			}
 			return err
 		case <-cli.Ctx().Done():
-			log.Println("service closed")
+			logger.Info("service closed", logger.F("addr", addr))
 			return nil
 		case _, ok := <-ch:
 			if !ok {
-				log.Println("keep alive channel closed")
+				logger.Warn("keep alive channel closed", logger.F("addr", addr))
 				_, err := cli.Revoke(context.Background(), leaseId)
 				return err
 			}
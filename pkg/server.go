@@ -2,41 +2,72 @@ package kache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/falldio/Kache/pkg/config"
 	"github.com/falldio/Kache/pkg/consistenthash"
+	"github.com/falldio/Kache/pkg/logger"
 	pb "github.com/falldio/Kache/pkg/proto"
 	"github.com/falldio/Kache/pkg/registry"
-	log "github.com/sirupsen/logrus"
-	clientv3 "go.etcd.io/etcd/client/v3"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
 	pb.UnimplementedKacheServer
 	self    string // address:port
 	mu      sync.Mutex
-	peers   *consistenthash.Map
+	peers   consistenthash.PeerSelector
 	running bool
 	stopCh  chan error
 	clients map[string]*Client
+	grpc    *grpc.Server
+	wg      sync.WaitGroup // registry goroutines
+
+	// invalidator tells the watching peers about the keys changed on this node
+	invalidator *invalidator
+
+	// fixedPeers is set once SetPeers is called, otherwise
+	// peers are discovered from etcd
+	fixedPeers bool
 }
 
-func NewServer(self string) *Server {
-	return &Server{
-		self: self,
+// NewServer creates the server of the node at self, allocating keys to
+// peers with config.Config.PeerSelector
+func NewServer(self string) (*Server, error) {
+	peers, err := consistenthash.NewSelector(config.Config.PeerSelector, config.Config.DefaultReplicas, nil)
+	if err != nil {
+		return nil, err
 	}
+	return &Server{
+		self:        self,
+		peers:       peers,
+		invalidator: newInvalidator(),
+	}, nil
 }
 
 func (s *Server) Get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	start := time.Now()
+	resp, err := s.get(ctx, in)
+	observeServerGet(in.GetGroup(), err, time.Since(start))
+	return resp, err
+}
+
+func (s *Server) get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
 	group, key := in.GetGroup(), in.GetKey()
 	resp := &pb.Response{}
 
-	log.Printf("[%s] Receives RPC Get request: %s/%s", s.self, group, key)
+	if logger.DebugEnabled() {
+		logger.Debug("rpc get", logger.F("node", s.self), logger.F("group", group), logger.F("key", key))
+	}
 	if key == "" {
 		return resp, fmt.Errorf("key required")
 	}
@@ -44,47 +75,146 @@ func (s *Server) Get(ctx context.Context, in *pb.Request) (*pb.Response, error)
 	if g == nil {
 		return resp, fmt.Errorf("group not found")
 	}
-	view, err := g.Get(key)
+	g.stats.serverRequests.Add(1)
+	// the deadline and cancellation of the RPC reach the getter
+	view, err := g.GetContext(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return resp, status.Error(codes.NotFound, err.Error())
+	}
 	if err != nil {
 		return resp, err
 	}
 	resp.Value = view.ByteSlice()
+	// a duration rather than a time, which the clock of the peer would skew
+	if e := view.Expire(); !e.IsZero() {
+		resp.Ttl = int64(time.Until(e))
+		if resp.Ttl == 0 {
+			resp.Ttl = -1
+		}
+	}
+	resp.Stale = view.Stale()
+	return resp, nil
+}
 
-	// update hot cache
-	go func() {
-		err = s.Update(group, key, resp.Value)
-		if err != nil {
-			log.Errorf("[%s] Updating %s/%s: %v", s.self, group, key, err)
+func (s *Server) Set(ctx context.Context, in *pb.SetRequest) (*pb.SetResponse, error) {
+	group, key := in.GetGroup(), in.GetKey()
+	resp := &pb.SetResponse{}
+
+	if logger.DebugEnabled() {
+		logger.Debug("rpc set", logger.F("node", s.self), logger.F("group", group), logger.F("key", key))
+	}
+	if key == "" {
+		return resp, fmt.Errorf("key required")
+	}
+	g := GetGroup(group)
+	if g == nil {
+		return resp, fmt.Errorf("group not found")
+	}
+	// the sender has routed key to this node, don't route it again
+	g.setLocally(key, in.GetValue(), time.Duration(in.GetTtl()))
+	return resp, nil
+}
+
+func (s *Server) Delete(ctx context.Context, in *pb.Request) (*pb.DeleteResponse, error) {
+	group, key := in.GetGroup(), in.GetKey()
+	resp := &pb.DeleteResponse{}
+
+	if logger.DebugEnabled() {
+		logger.Debug("rpc delete", logger.F("node", s.self), logger.F("group", group), logger.F("key", key))
+	}
+	if key == "" {
+		return resp, fmt.Errorf("key required")
+	}
+	g := GetGroup(group)
+	if g == nil {
+		return resp, fmt.Errorf("group not found")
+	}
+	g.removeLocally(key)
+	return resp, nil
+}
+
+// Stats reports the counters of group, or of every group if group is empty
+func (s *Server) Stats(ctx context.Context, in *pb.StatsRequest) (*pb.StatsResponse, error) {
+	resp := &pb.StatsResponse{}
+	if group := in.GetGroup(); group != "" {
+		g := GetGroup(group)
+		if g == nil {
+			return resp, status.Errorf(codes.NotFound, "group %s not found", group)
 		}
-	}()
+		resp.Groups = append(resp.Groups, statsToProto(group, g.Stats()))
+		return resp, nil
+	}
+	for name, g := range allGroups() {
+		resp.Groups = append(resp.Groups, statsToProto(name, g.Stats()))
+	}
 	return resp, nil
 }
 
+// Watch streams the keys changed on this node to a peer, until either side
+// goes away. An empty batch is sent first to tell the peer that it is
+// subscribed.
+func (s *Server) Watch(in *pb.WatchRequest, stream pb.Kache_WatchServer) error {
+	s.mu.Lock()
+	inv := s.invalidator
+	s.mu.Unlock()
+
+	sub := inv.subscribe()
+	defer inv.unsubscribe(sub)
+	if err := stream.Send(&pb.InvalidationBatch{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case batch, ok := <-sub.ch:
+			if !ok {
+				return status.Error(codes.Unavailable, "invalidation stream closed")
+			}
+			if err := stream.Send(batch); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
 func (s *Server) Start() error {
 	s.mu.Lock()
 	if s.running {
 		s.mu.Unlock()
 		return fmt.Errorf("server already started")
 	}
-	s.running = true
-	s.stopCh = make(chan error)
 
 	port := strings.Split(s.self, ":")[1]
 	ln, err := net.Listen("tcp", ":"+port)
 	if err != nil {
+		s.mu.Unlock()
 		return fmt.Errorf("starting to listen on %s: %w", port, err)
 	}
-	grpcServer := grpc.NewServer()
-	pb.RegisterKacheServer(grpcServer, s)
+	s.running = true
+	s.stopCh = make(chan error)
+	grpcServer := s.newGRPCServer()
+	s.grpc = grpcServer
 
 	// register service to etcd
+	s.wg.Add(1)
 	go func() {
-		err := registry.Register("kache", s.self, s.stopCh)
+		defer s.wg.Done()
+		md := registry.Metadata{Weight: config.Config.Weight}
+		err := registry.Register("kache", s.self, md, s.stopCh)
 		if err != nil {
-			log.Fatalf(err.Error())
+			logger.Error("registering service", logger.F("node", s.self), logger.Err(err))
+			os.Exit(1)
 		}
-		log.Printf("[%s] Revoke service and close tcp socket", s.self)
+		logger.Info("service revoked", logger.F("node", s.self))
 	}()
+	if !s.fixedPeers {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.watchPeers(s.stopCh)
+		}()
+	}
 
 	s.mu.Unlock()
 
@@ -94,21 +224,112 @@ func (s *Server) Start() error {
 	return nil
 }
 
-func (s *Server) SetPeers(peersAddr ...string) {
+// newGRPCServer returns a gRPC server answering the RPCs of peers with s
+func (s *Server) newGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(traceServerUnary))
+	pb.RegisterKacheServer(grpcServer, s)
+	return grpcServer
+}
+
+// SetPeers pins the members of the cluster to peersAddr, all of them with
+// the same weight. Servers that never call SetPeers or SetWeightedPeers
+// discover their peers from etcd once started.
+func (s *Server) SetPeers(peersAddr ...string) error {
+	weights := make(map[string]int, len(peersAddr))
+	for _, peerAddr := range peersAddr {
+		weights[peerAddr] = 1
+	}
+	return s.SetWeightedPeers(weights)
+}
+
+// SetWeightedPeers pins the members of the cluster to the keys of weights.
+// A peer owns a share of the keys proportional to its weight.
+func (s *Server) SetWeightedPeers(weights map[string]int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.peers == nil {
-		s.peers = consistenthash.New(config.Config.DefaultReplicas, nil)
-	}
-	s.peers.Add(peersAddr...)
-	s.clients = map[string]*Client{}
-	for _, peerAddr := range peersAddr {
+	for peerAddr := range weights {
 		if !validPeerAddr(peerAddr) {
-			panic(fmt.Sprintf("[peer %s] invalid addr\n", peerAddr))
+			return fmt.Errorf("peer %s: invalid addr", peerAddr)
 		}
-		service := fmt.Sprintf("kache/%s", peerAddr)
-		s.clients[peerAddr] = NewClient(service)
+	}
+	s.fixedPeers = true
+	s.updatePeers(weights)
+	return nil
+}
+
+// ParsePeers turns peer addresses, each optionally followed by =weight, into
+// the weights taken by SetWeightedPeers. A peer without a weight weighs 1.
+func ParsePeers(peers ...string) (map[string]int, error) {
+	weights := make(map[string]int, len(peers))
+	for _, peer := range peers {
+		peerAddr, weight := peer, 1
+		if i := strings.LastIndex(peer, "="); i >= 0 {
+			w, err := strconv.Atoi(peer[i+1:])
+			if err != nil || w < 1 {
+				return nil, fmt.Errorf("peer %s: invalid weight", peer)
+			}
+			peerAddr, weight = peer[:i], w
+		}
+		if _, ok := weights[peerAddr]; ok {
+			return nil, fmt.Errorf("peer %s: listed twice", peerAddr)
+		}
+		weights[peerAddr] = weight
+	}
+	return weights, nil
+}
+
+// watchPeers keeps the members of the cluster in sync with the nodes
+// registered in etcd, until stop is closed
+func (s *Server) watchPeers(stop chan error) {
+	err := registry.Watch("kache", stop, func(weights map[string]int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if !s.running {
+			return
+		}
+		for peerAddr := range weights {
+			if !validPeerAddr(peerAddr) {
+				logger.Warn("ignoring peer with invalid addr", logger.F("node", s.self), logger.F("peer", peerAddr))
+				delete(weights, peerAddr)
+			}
+		}
+		s.updatePeers(weights)
+	})
+	if err != nil {
+		logger.Error("watching peers", logger.F("node", s.self), logger.Err(err))
+	}
+}
+
+// updatePeers makes the keys of weights the members of the cluster, clients
+// of the peers that are still alive are kept.
+// s.mu must be held.
+func (s *Server) updatePeers(weights map[string]int) {
+	clients := make(map[string]*Client, len(weights))
+	joined := make([]string, 0)
+	for peerAddr, weight := range weights {
+		if c, ok := s.clients[peerAddr]; ok {
+			clients[peerAddr] = c
+		} else {
+			clients[peerAddr] = NewClient(peerAddr)
+			joined = append(joined, peerAddr)
+		}
+		// no-op unless the peer is new or its weight changed
+		s.peers.AddWithWeight(peerAddr, weight)
+	}
+	left := make([]string, 0)
+	for peerAddr, c := range s.clients {
+		if _, ok := clients[peerAddr]; !ok {
+			left = append(left, peerAddr)
+			c.Close()
+		}
+	}
+	s.clients = clients
+	s.peers.Remove(left...)
+
+	if len(joined) > 0 || len(left) > 0 {
+		logger.Info("peers updated", logger.F("node", s.self), logger.F("joined", joined), logger.F("left", left))
 	}
 }
 
@@ -130,37 +351,57 @@ func (s *Server) PickPeer(key string) (PeerGetter, bool) {
 	defer s.mu.Unlock()
 
 	peerAddr := s.peers.Get(key)
-	if peerAddr == s.self {
-		log.Printf("[%s] this key is allocated to the local node\n", s.self)
+	if peerAddr == "" || peerAddr == s.self {
 		return nil, false
 	}
-	log.Printf("[%s] Pickk remote peer: %s\n", s.self, peerAddr)
+	if logger.DebugEnabled() {
+		logger.Debug("picked peer", logger.F("node", s.self), logger.F("key", key), logger.F("peer", peerAddr))
+	}
 	return s.clients[peerAddr], true
 }
 
 func (s *Server) Stop() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if !s.running {
+		s.mu.Unlock()
 		return
 	}
-	s.stopCh <- nil
+	// stop registry first so that peers stop routing keys to this node
+	close(s.stopCh)
 	s.running = false
+	grpcServer := s.grpc
+	s.grpc = nil
+	// in-flight RPCs may still pick peers, from an empty selector that
+	// a restart fills again
+	for peerAddr, c := range s.clients {
+		c.Close()
+		s.peers.Remove(peerAddr)
+	}
 	s.clients = nil
-	s.peers = nil
+	// end the watch streams, or GracefulStop would wait for them forever
+	s.invalidator.close()
+	s.invalidator = newInvalidator()
+	s.mu.Unlock()
+
+	// in-flight RPCs may still pick peers, so wait for them without holding the lock
+	grpcServer.GracefulStop()
+	s.wg.Wait()
 }
 
-func (s *Server) Update(group, key string, value []byte) error {
-	cli, err := clientv3.New(registry.DefaultETCDConfig)
-	if err != nil {
-		return fmt.Errorf("creating etcd client: %w", err)
-	}
-	defer cli.Close()
-	_, err = cli.Put(context.Background(), fmt.Sprintf("/%s/%s", group, key), string(value))
-	if err != nil {
-		return fmt.Errorf("updating %s/%s: %w", group, key, err)
-	}
+// peerCount returns the members of the cluster, this node included
+func (s *Server) peerCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// Invalidate queues key for the peers watching this node, it never fails
+func (s *Server) Invalidate(group, key string) error {
+	s.mu.Lock()
+	inv := s.invalidator
+	s.mu.Unlock()
+
+	inv.add(group, key)
 	return nil
 }
 
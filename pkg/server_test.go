@@ -0,0 +1,100 @@
+package kache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/falldio/Kache/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer(t *testing.T, self string) *Server {
+	s, err := NewServer(self)
+	if err != nil {
+		t.Fatalf("creating server: %v", err)
+	}
+	return s
+}
+
+func TestNewServer(t *testing.T) {
+	defer func(kind string) { config.Config.PeerSelector = kind }(config.Config.PeerSelector)
+	config.Config.PeerSelector = "ringg"
+	_, err := NewServer("localhost:8001")
+	assert.ErrorContains(t, err, "unknown peer selector")
+}
+
+func TestSetPeers(t *testing.T) {
+	s := newServer(t, "localhost:8001")
+	assert.NotNil(t, s.SetPeers("8001"))
+	assert.False(t, s.fixedPeers)
+
+	assert.Nil(t, s.SetPeers("localhost:8001", "localhost:8002"))
+	assert.True(t, s.fixedPeers)
+	assert.Len(t, s.clients, 2)
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		peer, ok := s.PickPeer(key)
+		if ok {
+			assert.Equal(t, s.clients["localhost:8002"], peer)
+		}
+	}
+}
+
+func TestSetWeightedPeers(t *testing.T) {
+	s := newServer(t, "localhost:8001")
+	assert.Nil(t, s.SetWeightedPeers(map[string]int{"localhost:8001": 1, "localhost:8002": 4}))
+	remote := 0
+	for i := 0; i < 1000; i++ {
+		if _, ok := s.PickPeer(fmt.Sprintf("key-%d", i)); ok {
+			remote++
+		}
+	}
+	assert.Greater(t, remote, 500)
+}
+
+func TestParsePeers(t *testing.T) {
+	weights, err := ParsePeers("localhost:8001", "localhost:8002=4")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"localhost:8001": 1, "localhost:8002": 4}, weights)
+
+	for _, peers := range [][]string{
+		{"localhost:8001=heavy"},
+		{"localhost:8001=0"},
+		{"localhost:8001", "localhost:8001=2"},
+	} {
+		_, err := ParsePeers(peers...)
+		assert.NotNil(t, err, peers)
+	}
+}
+
+func TestUpdatePeers(t *testing.T) {
+	s := newServer(t, "localhost:8001")
+	s.updatePeers(map[string]int{"localhost:8001": 1, "localhost:8002": 1})
+	c2 := s.clients["localhost:8002"]
+
+	// joining peers are added, clients of alive peers are kept
+	s.updatePeers(map[string]int{"localhost:8001": 1, "localhost:8002": 2, "localhost:8003": 1})
+	assert.Len(t, s.clients, 3)
+	assert.Same(t, c2, s.clients["localhost:8002"])
+
+	// leaving peers are dropped from both the clients and the ring
+	s.updatePeers(map[string]int{"localhost:8001": 1})
+	assert.Len(t, s.clients, 1)
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		_, ok := s.PickPeer(key)
+		assert.False(t, ok)
+	}
+}
+
+func TestPickPeerAfterStop(t *testing.T) {
+	s := newServer(t, "localhost:8001")
+	assert.Nil(t, s.SetPeers("localhost:8001", "localhost:8002"))
+	s.running, s.stopCh, s.grpc = true, make(chan error), s.newGRPCServer()
+	s.Stop()
+
+	// RPCs draining after Stop load keys locally
+	_, ok := s.PickPeer("Tom")
+	assert.False(t, ok)
+	// and peers can be set again for a restart
+	assert.Nil(t, s.SetPeers("localhost:8001", "localhost:8002"))
+	assert.Len(t, s.clients, 2)
+}
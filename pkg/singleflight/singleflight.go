@@ -1,11 +1,18 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 type call struct {
-	wg  sync.WaitGroup
-	val any
-	err error
+	done chan struct{}
+	val  any
+	err  error
+
+	// callers still waiting for the result, fn is cancelled when none is left
+	waiters int
+	cancel  context.CancelFunc
 }
 
 type Group struct {
@@ -14,26 +21,62 @@ type Group struct {
 }
 
 func (g *Group) Do(key string, fn func() (any, error)) (any, error) {
+	return g.DoContext(context.Background(), key, func(context.Context) (any, error) {
+		return fn()
+	})
+}
+
+// DoContext is like Do, but a caller returns ctx.Err() as soon as its ctx is
+// done. fn is given a context carrying the values of the first caller's ctx,
+// which is cancelled once every caller waiting for key has given up.
+func (g *Group) DoContext(ctx context.Context, key string, fn func(context.Context) (any, error)) (any, error) {
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
+		c.waiters++
 		g.mu.Unlock()
-		c.wg.Wait()
-		return c.val, c.err
+		return g.wait(ctx, key, c)
+	}
+	fnCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &call{
+		done:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
 	}
-	c := new(call)
-	c.wg.Add(1)
 	g.m[key] = c
 	g.mu.Unlock()
 
-	c.val, c.err = fn()
-	c.wg.Done()
+	go func() {
+		c.val, c.err = fn(fnCtx)
+		cancel()
 
-	g.mu.Lock()
-	delete(g.m, key)
-	g.mu.Unlock()
+		g.mu.Lock()
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	return g.wait(ctx, key, c)
+}
 
-	return c.val, c.err
+func (g *Group) wait(ctx context.Context, key string, c *call) (any, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			// later callers start a new call instead of joining a cancelled one
+			if g.m[key] == c {
+				delete(g.m, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
 }
@@ -1,6 +1,7 @@
 package singleflight
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -47,3 +48,69 @@ func TestGroup_Do(t *testing.T) {
 		t.Error("expected function not to be called")
 	}
 }
+
+func TestGroup_DoContext(t *testing.T) {
+	g := &Group{}
+
+	// a caller giving up doesn't cancel fn while others still wait
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		_, err := g.DoContext(ctx1, "key", func(ctx context.Context) (any, error) {
+			close(started)
+			select {
+			case <-release:
+				return "value", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+		errCh <- err
+	}()
+	<-started
+	valCh := make(chan any)
+	go func() {
+		v, _ := g.DoContext(context.Background(), "key", func(ctx context.Context) (any, error) {
+			t.Error("expected function not to be called")
+			return nil, nil
+		})
+		valCh <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel1()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	close(release)
+	if v := <-valCh; v != "value" {
+		t.Errorf("expected value %q, got %q", "value", v)
+	}
+
+	// fn is cancelled once every caller gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	cancelled := make(chan struct{})
+	_, err := g.DoContext(ctx, "key", func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected function to be cancelled")
+	}
+
+	// later callers don't join the cancelled call
+	v, err := g.DoContext(context.Background(), "key", func(ctx context.Context) (any, error) {
+		return "value", nil
+	})
+	if err != nil || v != "value" {
+		t.Errorf("expected value %q, got %q, %v", "value", v, err)
+	}
+}
@@ -0,0 +1,231 @@
+package kache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/falldio/Kache/pkg/cache"
+	"github.com/falldio/Kache/pkg/logger"
+	pb "github.com/falldio/Kache/pkg/proto"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// A snapshot file starts with snapshotMagic and the version of its format,
+// followed by length-delimited pb.SnapshotEntry messages.
+const (
+	snapshotMagic   = "KACHESNP"
+	snapshotVersion = 1
+)
+
+// SaveSnapshot writes the mainCache of every group to path, replacing the
+// file only once the snapshot is complete. hotCache isn't saved, its copies
+// would no longer be invalidated by their owners once restored. The
+// modification time of the file is when the snapshot was taken.
+func SaveSnapshot(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer os.Remove(f.Name()) // fails once renamed
+	takenAt := time.Now()
+	if err := writeSnapshot(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing snapshot: %w", err)
+	}
+	// writes made while saving may or may not be in the snapshot
+	if err := os.Chtimes(f.Name(), takenAt, takenAt); err != nil {
+		return fmt.Errorf("dating snapshot: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot: %w", err)
+	}
+	return nil
+}
+
+func writeSnapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.BigEndian, uint32(snapshotVersion)); err != nil {
+		return err
+	}
+
+	all := allGroups()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, e := range all[name].mainCache.Entries() {
+			if _, err := protodelim.MarshalTo(bw, snapshotEntry(name, e)); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// LoadSnapshot restores the entries saved to path by SaveSnapshot into the
+// groups of the same name, and returns how many of them were read. Entries
+// of groups that don't exist and expired entries are skipped, entries
+// already cached are kept as they are newer. A missing file restores nothing.
+//
+// Entries are restored as they were saved, whoever owns their key now: a
+// value set on another node while this one was down is shadowed by the one
+// saved here until it expires, forever without a TTL. A snapshot taken
+// before since restores nothing to bound that window, the zero time means
+// no limit.
+func LoadSnapshot(path string, since time.Time) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer f.Close()
+	if !since.IsZero() {
+		st, err := f.Stat()
+		if err != nil {
+			return 0, fmt.Errorf("opening snapshot: %w", err)
+		}
+		if takenAt := st.ModTime(); takenAt.Before(since) {
+			logger.Warn("skipping an outdated snapshot", logger.F("path", path), logger.F("taken_at", takenAt))
+			return 0, nil
+		}
+	}
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != snapshotMagic {
+		return 0, fmt.Errorf("%s is not a snapshot", path)
+	}
+	var version uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return 0, fmt.Errorf("reading snapshot version: %w", err)
+	}
+	if version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	n := 0
+	for {
+		e := &pb.SnapshotEntry{}
+		err := protodelim.UnmarshalFrom(r, e)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			// the entries read so far are restored all the same
+			return n, fmt.Errorf("reading snapshot entry %d: %w", n, err)
+		}
+		n++
+		if g := GetGroup(e.GetGroup()); g != nil {
+			g.restore(snapshotCacheEntry(e))
+		}
+	}
+}
+
+// StartSnapshotter saves a snapshot to path every interval in the
+// background, and once more when stopped so that a node shut down
+// gracefully loses nothing. A 0 interval only saves when stopped.
+func StartSnapshotter(path string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-tick:
+				if err := SaveSnapshot(path); err != nil {
+					logger.Error("saving snapshot", logger.F("path", path), logger.Err(err))
+				}
+			case <-done:
+				if err := SaveSnapshot(path); err != nil {
+					logger.Error("saving snapshot", logger.F("path", path), logger.Err(err))
+				}
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// restore adds e to mainCache, unless a newer entry is there already
+func (g *Group) restore(e cache.Entry) {
+	if g.cacheBytes <= 0 {
+		return
+	}
+	g.mainCache.Restore(e)
+	g.fit()
+}
+
+func snapshotEntry(group string, e cache.Entry) *pb.SnapshotEntry {
+	v := e.Value.(ByteView)
+	return &pb.SnapshotEntry{
+		Group:    group,
+		Key:      e.Key,
+		Value:    v.bts,
+		Expire:   unixNano(v.e),
+		Refresh:  unixNano(v.refresh),
+		NotFound: v.notFound,
+		Evict:    unixNano(e.Expire),
+		Freq:     e.Freq,
+		Set:      v.set,
+	}
+}
+
+func snapshotCacheEntry(e *pb.SnapshotEntry) cache.Entry {
+	return cache.Entry{
+		Key: e.GetKey(),
+		Value: ByteView{
+			bts:      e.GetValue(),
+			e:        fromUnixNano(e.GetExpire()),
+			refresh:  fromUnixNano(e.GetRefresh()),
+			notFound: e.GetNotFound(),
+			set:      e.GetSet(),
+		},
+		Expire: fromUnixNano(e.GetEvict()),
+		Freq:   e.GetFreq(),
+	}
+}
+
+// unixNano is t in unix nanoseconds, 0 for the zero time
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
@@ -0,0 +1,164 @@
+package kache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/falldio/Kache/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	dropGroups(t, "snapshot")
+	loads := 0
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		if key == "missing" {
+			return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}
+		return []byte(key), nil
+	})
+	opts := []GroupOption{
+		WithCacheBytes(2 << 10),
+		WithStrategy(cache.CACHE_STRATEGY_LFU),
+		WithDefaultTTL(time.Hour),
+		WithNegativeTTL(time.Hour),
+	}
+	g, err := NewGroupWithOptions("snapshot", getter, opts...)
+	assert.Nil(t, err)
+	g.Set("forever", []byte("630"), 0)
+	for _, key := range []string{"Tom", "Tom", "Tom", "Jack", "missing"} {
+		g.Get(key)
+	}
+	g.Set("expiring", []byte("589"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "kache.snapshot")
+	assert.Nil(t, SaveSnapshot(path))
+	saved := wallClock(g.mainCache.Entries())
+
+	// a restarted node starts from the snapshot instead of the getter
+	g = NewGroup("snapshot", 2<<10, getter, opts...)
+	_, err = LoadSnapshot(path, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, saved, wallClock(g.mainCache.Entries()))
+
+	loads = 0
+	v, err := g.Get("Tom")
+	assert.Nil(t, err)
+	assert.Equal(t, "Tom", v.String())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), v.Expire(), time.Second)
+	v, err = g.Get("forever")
+	assert.Nil(t, err)
+	assert.Equal(t, "630", v.String())
+	assert.True(t, v.Expire().IsZero())
+	_, err = g.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 0, loads)
+
+	// cached entries are newer than those of the snapshot
+	g.Set("Jack", []byte("newer"), 0)
+	_, err = LoadSnapshot(path, time.Time{})
+	assert.Nil(t, err)
+	v, _ = g.Get("Jack")
+	assert.Equal(t, "newer", v.String())
+}
+
+// wallClock strips the monotonic clock readings from the times of entries,
+// which snapshots don't keep
+func wallClock(entries []cache.Entry) []cache.Entry {
+	for i, e := range entries {
+		v := e.Value.(ByteView)
+		v.e, v.refresh = v.e.Round(0), v.refresh.Round(0)
+		entries[i].Value, entries[i].Expire = v, e.Expire.Round(0)
+	}
+	return entries
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+	n, err := LoadSnapshot(filepath.Join(dir, "none"), time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	path := filepath.Join(dir, "bad")
+	assert.Nil(t, os.WriteFile(path, []byte("not a snapshot"), 0o644))
+	_, err = LoadSnapshot(path, time.Time{})
+	assert.NotNil(t, err)
+
+	version := binary.BigEndian.AppendUint32([]byte(snapshotMagic), snapshotVersion+1)
+	assert.Nil(t, os.WriteFile(path, version, 0o644))
+	_, err = LoadSnapshot(path, time.Time{})
+	assert.ErrorContains(t, err, "unsupported snapshot version")
+
+	// the entries before a truncated one are restored
+	dropGroups(t, "snapshot-truncated")
+	g := NewGroup("snapshot-truncated", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	g.Get("Tom")
+	assert.Nil(t, SaveSnapshot(path))
+	all, err := LoadSnapshot(path, time.Time{})
+	assert.Nil(t, err)
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(path, data[:len(data)-1], 0o644))
+	n, err = LoadSnapshot(path, time.Time{})
+	assert.NotNil(t, err)
+	assert.Equal(t, all-1, n)
+}
+
+func TestLoadSnapshotSince(t *testing.T) {
+	dropGroups(t, "snapshot-age")
+	g := NewGroup("snapshot-age", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	g.Set("Tom", []byte("630"), 0)
+	path := filepath.Join(t.TempDir(), "kache.snapshot")
+	assert.Nil(t, SaveSnapshot(path))
+	g.mainCache.Remove("Tom")
+
+	// a node down for too long starts empty
+	old := time.Now().Add(-time.Hour)
+	assert.Nil(t, os.Chtimes(path, old, old))
+	n, err := LoadSnapshot(path, time.Now().Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	assert.False(t, g.mainCache.Has("Tom"))
+
+	_, err = LoadSnapshot(path, time.Now().Add(-2*time.Hour))
+	assert.Nil(t, err)
+	assert.True(t, g.mainCache.Has("Tom"))
+}
+
+func TestStartSnapshotter(t *testing.T) {
+	dropGroups(t, "snapshotter")
+	g := NewGroup("snapshotter", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	path := filepath.Join(t.TempDir(), "kache.snapshot")
+
+	stop := StartSnapshotter(path, 10*time.Millisecond)
+	g.Get("Tom")
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	// stopping saves what was cached in between
+	g.Get("Jack")
+	stop()
+	g.mainCache.Remove("Tom")
+	g.mainCache.Remove("Jack")
+	_, err := LoadSnapshot(path, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, g.mainCache.Len())
+
+	// no temporary file is left behind
+	files, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, files, 1)
+}
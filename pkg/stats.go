@@ -0,0 +1,108 @@
+package kache
+
+import (
+	"sync/atomic"
+
+	"github.com/falldio/Kache/pkg/cache"
+	pb "github.com/falldio/Kache/pkg/proto"
+)
+
+// Stats are the counters of a group since it was created. Keys not found
+// are answers like any other, loading them counts as a load, not an error.
+type Stats struct {
+	Gets           int64 // lookups, including those of peers
+	CacheHits      int64 // lookups answered by either cache
+	StaleHits      int64 // lookups answered by an expired value that failed to load
+	Loads          int64 // lookups missing both caches
+	LoadsDeduped   int64 // loads left once concurrent ones are merged
+	PeerLoads      int64 // loads answered by the owner of the key
+	PeerErrors     int64 // loads the owner of the key failed to answer
+	LocalLoads     int64 // loads answered by the getter
+	LocalLoadErrs  int64 // loads the getter failed to answer
+	ServerRequests int64 // lookups made by peers
+
+	MainCache cache.Stats
+	HotCache  cache.Stats
+}
+
+// groupStats are updated by every lookup of a group
+type groupStats struct {
+	gets, cacheHits, staleHits, loads, loadsDeduped  atomic.Int64
+	peerLoads, peerErrors, localLoads, localLoadErrs atomic.Int64
+	serverRequests                                   atomic.Int64
+}
+
+// Stats returns the counters of g and of both its caches
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:           g.stats.gets.Load(),
+		CacheHits:      g.stats.cacheHits.Load(),
+		StaleHits:      g.stats.staleHits.Load(),
+		Loads:          g.stats.loads.Load(),
+		LoadsDeduped:   g.stats.loadsDeduped.Load(),
+		PeerLoads:      g.stats.peerLoads.Load(),
+		PeerErrors:     g.stats.peerErrors.Load(),
+		LocalLoads:     g.stats.localLoads.Load(),
+		LocalLoadErrs:  g.stats.localLoadErrs.Load(),
+		ServerRequests: g.stats.serverRequests.Load(),
+		MainCache:      g.mainCache.Stats(),
+		HotCache:       g.hotCache.Stats(),
+	}
+}
+
+func statsToProto(group string, s Stats) *pb.GroupStats {
+	return &pb.GroupStats{
+		Group:          group,
+		Gets:           s.Gets,
+		CacheHits:      s.CacheHits,
+		StaleHits:      s.StaleHits,
+		Loads:          s.Loads,
+		LoadsDeduped:   s.LoadsDeduped,
+		PeerLoads:      s.PeerLoads,
+		PeerErrors:     s.PeerErrors,
+		LocalLoads:     s.LocalLoads,
+		LocalLoadErrs:  s.LocalLoadErrs,
+		ServerRequests: s.ServerRequests,
+		MainCache:      cacheStatsToProto(s.MainCache),
+		HotCache:       cacheStatsToProto(s.HotCache),
+	}
+}
+
+func statsFromProto(s *pb.GroupStats) Stats {
+	return Stats{
+		Gets:           s.GetGets(),
+		CacheHits:      s.GetCacheHits(),
+		StaleHits:      s.GetStaleHits(),
+		Loads:          s.GetLoads(),
+		LoadsDeduped:   s.GetLoadsDeduped(),
+		PeerLoads:      s.GetPeerLoads(),
+		PeerErrors:     s.GetPeerErrors(),
+		LocalLoads:     s.GetLocalLoads(),
+		LocalLoadErrs:  s.GetLocalLoadErrs(),
+		ServerRequests: s.GetServerRequests(),
+		MainCache:      cacheStatsFromProto(s.GetMainCache()),
+		HotCache:       cacheStatsFromProto(s.GetHotCache()),
+	}
+}
+
+func cacheStatsToProto(s cache.Stats) *pb.CacheStats {
+	return &pb.CacheStats{
+		Hits:        s.Hits,
+		Misses:      s.Misses,
+		Evictions:   s.Evictions,
+		Expirations: s.Expirations,
+		Bytes:       s.Bytes,
+		Items:       s.Items,
+	}
+}
+
+func cacheStatsFromProto(s *pb.CacheStats) cache.Stats {
+	return cache.Stats{
+		Hits:        s.GetHits(),
+		Misses:      s.GetMisses(),
+		Evictions:   s.GetEvictions(),
+		Expirations: s.GetExpirations(),
+		Bytes:       s.GetBytes(),
+		Items:       s.GetItems(),
+	}
+}
@@ -0,0 +1,73 @@
+package kache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/falldio/Kache/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupStats(t *testing.T) {
+	dropGroups(t, "stats")
+	g, err := NewGroupWithOptions("stats", GetterFunc(func(key string) ([]byte, error) {
+		switch key {
+		case "Tom":
+			return []byte("630"), nil
+		case "broken":
+			return nil, errors.New("db is down")
+		}
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}), WithCacheBytes(2<<10))
+	assert.Nil(t, err)
+
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("Jack")
+	g.Get("broken")
+
+	s := g.Stats()
+	assert.Equal(t, int64(4), s.Gets)
+	assert.Equal(t, int64(1), s.CacheHits)
+	assert.Equal(t, int64(3), s.Loads)
+	assert.Equal(t, int64(3), s.LoadsDeduped)
+	// a key not found is loaded all right
+	assert.Equal(t, int64(2), s.LocalLoads)
+	assert.Equal(t, int64(1), s.LocalLoadErrs)
+	assert.Equal(t, int64(0), s.PeerLoads)
+	assert.Equal(t, cache.Stats{Hits: 1, Misses: 3, Bytes: 6, Items: 1}, s.MainCache)
+	assert.Equal(t, int64(3), s.HotCache.Misses)
+}
+
+func TestClientStats(t *testing.T) {
+	dropGroups(t, "stats-a", "stats-b")
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	_, err := NewGroupWithOptions("stats-a", getter, WithCacheBytes(2<<10))
+	assert.Nil(t, err)
+	_, err = NewGroupWithOptions("stats-b", getter, WithCacheBytes(2<<10))
+	assert.Nil(t, err)
+	c := NewClient(startPeer(t))
+	defer c.Close()
+
+	_, err = c.Get(context.Background(), "stats-a", "Tom")
+	assert.Nil(t, err)
+	stats, err := c.Stats(context.Background(), "stats-a")
+	assert.Nil(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, int64(1), stats["stats-a"].ServerRequests)
+	assert.Equal(t, int64(1), stats["stats-a"].LocalLoads)
+	assert.Equal(t, int64(1), stats["stats-a"].MainCache.Items)
+
+	// every group of the peer is reported without a name
+	stats, err = c.Stats(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Contains(t, stats, "stats-a")
+	assert.Contains(t, stats, "stats-b")
+
+	_, err = c.Stats(context.Background(), "unknown")
+	assert.NotNil(t, err)
+}
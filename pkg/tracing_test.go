@@ -0,0 +1,108 @@
+package kache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans reports the spans ended until the test is over to the
+// returned exporter
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(old)
+		tp.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// groupPeer picks a single peer for every key, asking it for another group
+// so that both ends of the hop can live in the same process
+type groupPeer struct {
+	client *Client
+	group  string
+}
+
+func (p *groupPeer) PickPeer(key string) (PeerGetter, bool) {
+	return p, true
+}
+
+func (p *groupPeer) Invalidate(group, key string) error {
+	return nil
+}
+
+func (p *groupPeer) Get(ctx context.Context, group, key string) (ByteView, error) {
+	return p.client.Get(ctx, p.group, key)
+}
+
+func (p *groupPeer) Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	return p.client.Set(ctx, p.group, key, value, ttl)
+}
+
+func (p *groupPeer) Delete(ctx context.Context, group, key string) error {
+	return p.client.Delete(ctx, p.group, key)
+}
+
+func (p *groupPeer) Watch(ctx context.Context, group, key string, onInvalidated func()) (func(), error) {
+	return p.client.Watch(ctx, p.group, key, onInvalidated)
+}
+
+func TestTracePeerHop(t *testing.T) {
+	exporter := recordSpans(t)
+	dropGroups(t, "trace-front", "trace-back")
+	_, err := NewGroupWithOptions("trace-back", GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), WithCacheBytes(2<<10))
+	assert.Nil(t, err)
+	c := NewClient(startPeer(t))
+	defer c.Close()
+	front, err := NewGroupWithOptions("trace-front", GetterFunc(func(key string) ([]byte, error) {
+		t.Fatalf("expect %s loaded by the peer", key)
+		return nil, nil
+	}), WithCacheBytes(2<<10), WithPeers(&groupPeer{client: c, group: "trace-back"}))
+	assert.Nil(t, err)
+
+	_, err = front.Get("Tom")
+	assert.Nil(t, err)
+
+	// spans end from the innermost out, down the chain to the getter of
+	// the peer, which all belongs to a single trace
+	spans := exporter.GetSpans()
+	want := []struct {
+		name string
+		kind trace.SpanKind
+	}{
+		{"Group.getLocally", trace.SpanKindInternal},
+		{"Group.load", trace.SpanKindInternal},
+		{"Group.Get", trace.SpanKindInternal},
+		{"kachepb.Kache/Get", trace.SpanKindServer},
+		{"kachepb.Kache/Get", trace.SpanKindClient},
+		{"Group.getFromPeer", trace.SpanKindInternal},
+		{"Group.load", trace.SpanKindInternal},
+		{"Group.Get", trace.SpanKindInternal},
+	}
+	if !assert.Len(t, spans, len(want)) {
+		return
+	}
+	root := spans[len(spans)-1]
+	assert.False(t, root.Parent.IsValid())
+	for i, w := range want {
+		s := spans[i]
+		assert.Equal(t, w.name, s.Name)
+		assert.Equal(t, w.kind, s.SpanKind, w.name)
+		assert.Equal(t, root.SpanContext.TraceID(), s.SpanContext.TraceID(), w.name)
+		if i < len(spans)-1 {
+			assert.Equal(t, spans[i+1].SpanContext.SpanID(), s.Parent.SpanID(), w.name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package kache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/falldio/Kache/pkg/config"
+	"github.com/falldio/Kache/pkg/logger"
+)
+
+// watches running on peers for all the groups, bounded by config.Config.MaxWatches
+var activeWatches atomic.Int64
+
+// watchManager keeps a single watch on the owner of each key held in the
+// hotCache of a group, a key no longer held is no longer watched
+type watchManager struct {
+	mu      sync.Mutex
+	watches map[string]*hotWatch
+}
+
+type hotWatch struct {
+	cancel  func()      // stops watching on the peer
+	fired   atomic.Bool // whether the key has changed on the peer
+	release sync.Once   // frees the slot of the watch
+
+	// the watch is stopped once neither a load in flight nor a hot copy
+	// relies on it, both guarded by watchManager.mu
+	loads int
+	kept  bool
+}
+
+func newWatchManager() *watchManager {
+	return &watchManager{
+		watches: make(map[string]*hotWatch),
+	}
+}
+
+// watch makes sure that key is watched on peer, onInvalidated is called
+// once it changes. A nil watch is returned when key can't be watched, in
+// which case it shouldn't be kept as a hot copy either. Otherwise the watch
+// is shared with the other loads of key, and must be given back with done.
+func (m *watchManager) watch(ctx context.Context, peer PeerGetter, group, key string, onInvalidated func()) *hotWatch {
+	m.mu.Lock()
+	if w, ok := m.watches[key]; ok {
+		w.loads++
+		m.mu.Unlock()
+		return w
+	}
+	m.mu.Unlock()
+
+	if max := int64(config.Config.MaxWatches); activeWatches.Add(1) > max && max > 0 {
+		activeWatches.Add(-1)
+		return nil
+	}
+	w := &hotWatch{loads: 1}
+	cancel, err := peer.Watch(ctx, group, key, func() {
+		w.fired.Store(true)
+		m.remove(key, w)
+		onInvalidated()
+	})
+	if err != nil {
+		logger.Warn("watching, not keeping a hot copy", logger.F("group", group), logger.F("key", key), logger.Err(err))
+		w.free()
+		return nil
+	}
+	w.cancel = cancel
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if other, ok := m.watches[key]; ok {
+		// someone else has watched key in the meantime
+		w.cancel()
+		w.free()
+		other.loads++
+		return other
+	}
+	if !w.fired.Load() {
+		m.watches[key] = w
+	}
+	return w
+}
+
+// done ends a load of key relying on w, kept tells whether it has stored a
+// hot copy of key. w is stopped unless other loads or a copy rely on it.
+func (m *watchManager) done(key string, w *hotWatch, kept bool) {
+	m.mu.Lock()
+	w.loads--
+	if kept {
+		w.kept = true
+	}
+	if w.loads > 0 || w.kept {
+		m.mu.Unlock()
+		return
+	}
+	m.stopLocked(key, w)
+}
+
+// evicted stops the watch of key, whose hot copy is gone, unless a load in
+// flight relies on it
+func (m *watchManager) evicted(key string) {
+	m.mu.Lock()
+	w, ok := m.watches[key]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	w.kept = false
+	if w.loads > 0 {
+		m.mu.Unlock()
+		return
+	}
+	m.stopLocked(key, w)
+}
+
+// stopLocked forgets w and cancels it if it is the watch of key, m.mu must
+// be held and is released
+func (m *watchManager) stopLocked(key string, w *hotWatch) {
+	current := m.watches[key] == w
+	if current {
+		delete(m.watches, key)
+	}
+	m.mu.Unlock()
+
+	if current {
+		w.cancel()
+	}
+	w.free()
+}
+
+// remove forgets w and frees its slot
+func (m *watchManager) remove(key string, w *hotWatch) {
+	m.mu.Lock()
+	if m.watches[key] == w {
+		delete(m.watches, key)
+	}
+	m.mu.Unlock()
+
+	w.free()
+}
+
+func (w *hotWatch) free() {
+	w.release.Do(func() {
+		activeWatches.Add(-1)
+	})
+}
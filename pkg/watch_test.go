@@ -0,0 +1,94 @@
+package kache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/falldio/Kache/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWatchOncePerKey(t *testing.T) {
+	setHotKeyQPS(t, 0)
+	cancelled := 0
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeerGetter.On("Get", "watch-scores", "Tom").Return([]byte("630"), nil)
+	mockPeerGetter.On("Watch", "watch-scores", "Tom", mock.AnythingOfType("func()")).
+		Return(func() { cancelled++ }, nil)
+	g := NewGroup("watch-scores", 2<<10, mockGetter)
+
+	// fetching a watched key again doesn't watch it twice
+	for i := 0; i < 3; i++ {
+		_, err := g.getFromPeer(context.Background(), mockPeerGetter, "Tom")
+		assert.Nil(t, err)
+	}
+	mockPeerGetter.AssertNumberOfCalls(t, "Watch", 1)
+	assert.Len(t, g.watches.watches, 1)
+
+	// the watch ends with the hot copy
+	g.hotCache.Remove("Tom")
+	assert.Equal(t, 1, cancelled)
+	assert.Len(t, g.watches.watches, 0)
+	_, err := g.getFromPeer(context.Background(), mockPeerGetter, "Tom")
+	assert.Nil(t, err)
+	mockPeerGetter.AssertNumberOfCalls(t, "Watch", 2)
+	g.hotCache.Shrink()
+	assert.Equal(t, 2, cancelled)
+}
+
+func TestWatchLimit(t *testing.T) {
+	setHotKeyQPS(t, 0)
+	defer func(max int) { config.Config.MaxWatches = max }(config.Config.MaxWatches)
+	config.Config.MaxWatches = int(activeWatches.Load()) + 1
+
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeerGetter.On("Get", "watch-limit", mock.Anything).Return([]byte("630"), nil)
+	mockPeerGetter.On("Watch", "watch-limit", mock.Anything, mock.AnythingOfType("func()")).
+		Return(func() {}, nil)
+	g := NewGroup("watch-limit", 2<<10, mockGetter)
+
+	// keys beyond the limit are fetched, but not kept
+	_, err := g.getFromPeer(context.Background(), mockPeerGetter, "Tom")
+	assert.Nil(t, err)
+	v, err := g.getFromPeer(context.Background(), mockPeerGetter, "Jack")
+	assert.Nil(t, err)
+	assert.Equal(t, "630", v.String())
+	assert.True(t, g.hotCache.Has("Tom"))
+	assert.False(t, g.hotCache.Has("Jack"))
+	mockPeerGetter.AssertNumberOfCalls(t, "Watch", 1)
+
+	// a slot is freed once a hot copy is gone
+	g.hotCache.Remove("Tom")
+	_, err = g.getFromPeer(context.Background(), mockPeerGetter, "Jack")
+	assert.Nil(t, err)
+	assert.True(t, g.hotCache.Has("Jack"))
+}
+
+func TestWatchSharedByLoads(t *testing.T) {
+	cancelled := 0
+	mockPeerGetter := &MockPeerGetter{}
+	mockPeerGetter.On("Watch", "watch-shared", "Tom", mock.AnythingOfType("func()")).
+		Return(func() { cancelled++ }, nil)
+	g := NewGroup("watch-shared", 2<<10, mockGetter)
+	watch := func() *hotWatch {
+		return g.watches.watch(context.Background(), mockPeerGetter, g.name, "Tom", func() {})
+	}
+
+	// a load failing doesn't stop the watch another load relies on
+	w1, w2 := watch(), watch()
+	assert.Same(t, w1, w2)
+	g.watches.done("Tom", w1, false)
+	assert.Equal(t, 0, cancelled)
+	g.watches.done("Tom", w2, true)
+	assert.Equal(t, 0, cancelled)
+
+	// nor does the eviction of the copy while a load is in flight
+	w3 := watch()
+	g.watches.evicted("Tom")
+	assert.Equal(t, 0, cancelled)
+	g.watches.done("Tom", w3, false)
+	assert.Equal(t, 1, cancelled)
+	assert.Len(t, g.watches.watches, 0)
+	mockPeerGetter.AssertNumberOfCalls(t, "Watch", 1)
+}
@@ -2,14 +2,14 @@
 trap "rm server;kill 0" EXIT
 
 go build -o server
-./server --port 8001 &
-./server --port 8002 &
-./server --port 8003 --api 1 &
+./server --port 8001 --api=false &
+./server --port 8002 --api=false &
+./server --port 8003 --api=true --api_port 9999 &
 
 sleep 2
 echo ">>> start test"
-curl "http://localhost:8003/api?key=Tom"
-curl "http://localhost:8003/api?key=Tom"
-curl "http://localhost:8003/api?key=Tom"
+curl "http://localhost:9999/api?group=scores&key=Tom"
+curl "http://localhost:9999/api?group=scores&key=Tom"
+curl "http://localhost:9999/api?group=scores&key=Tom"
 
 wait